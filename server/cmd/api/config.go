@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/francis/projectx-api/internal/config"
+)
+
+const configUsage = `Usage: api config print [--config file] [--redacted=false]
+
+Prints the configuration that would take effect after merging the defaults,
+the config file and the environment, then reports any validation errors.
+Secrets are shown as REDACTED unless --redacted=false is given, which is
+meant for debugging a local setup.`
+
+// runConfig implements the "config" subcommand and returns the exit code.
+func runConfig(args []string) int {
+    if len(args) == 0 || args[0] != "print" {
+        fmt.Fprintln(os.Stderr, configUsage)
+        return 2
+    }
+
+    flags := flag.NewFlagSet("config print", flag.ExitOnError)
+    configPath := flags.String("config", "", "YAML or TOML config file (default $CONFIG_FILE)")
+    redact := flags.Bool("redacted", true, "show secrets as REDACTED")
+    flags.Usage = func() { fmt.Fprintln(os.Stderr, configUsage) }
+    flags.Parse(args[1:])
+
+    cfg, err := config.Read(*configPath)
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        return 1
+    }
+
+    write := cfg.WriteYAML
+    if !*redact {
+        write = cfg.WriteYAMLUnredacted
+    }
+    if err := write(os.Stdout); err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        return 1
+    }
+
+    if err := cfg.Validate(); err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        return 1
+    }
+    return 0
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"net/http"
 	"os"
@@ -9,94 +11,467 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/francis/projectx-api/internal/buildinfo"
 	"github.com/francis/projectx-api/internal/config"
 	"github.com/francis/projectx-api/internal/database"
+	"github.com/francis/projectx-api/internal/debugserver"
 	"github.com/francis/projectx-api/internal/handler"
+	"github.com/francis/projectx-api/internal/health"
+	"github.com/francis/projectx-api/internal/mailer"
+	"github.com/francis/projectx-api/internal/metrics"
 	"github.com/francis/projectx-api/internal/middleware"
+	"github.com/francis/projectx-api/internal/model"
 	"github.com/francis/projectx-api/internal/repository/postgres"
 	"github.com/francis/projectx-api/internal/service"
+	"github.com/francis/projectx-api/internal/tracing"
 	"github.com/francis/projectx-api/pkg/logger"
+	"github.com/francis/projectx-api/pkg/utils"
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
 )
 
 func main() {
+    args := os.Args[1:]
+    if len(args) > 0 && args[0] == "config" {
+        os.Exit(runConfig(args[1:]))
+    }
+    if len(args) > 0 && args[0] == "migrate" {
+        os.Exit(runMigrate(args[1:]))
+    }
+    if len(args) > 0 && args[0] == "serve" {
+        args = args[1:]
+    }
+
+    flags := flag.NewFlagSet("serve", flag.ExitOnError)
+    configPath := flags.String("config", "", "YAML or TOML config file (default $CONFIG_FILE)")
+    runMigrations := flags.Bool("migrate", false, "apply pending migrations before serving")
+    flags.Parse(args)
+
     // Load configuration
-    cfg := config.Load()
+    cfg, err := config.Load(*configPath)
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(1)
+    }
+
+    os.Exit(serve(config.NewReloader(*configPath, cfg), *runMigrations))
+}
+
+// signalDebugTTL is how long SIGUSR1 turns on debug logging.
+const signalDebugTTL = 15 * time.Minute
+
+// tracingFlushTimeout bounds sending the last spans on shutdown.
+const tracingFlushTimeout = 5 * time.Second
+
+// handlers groups the HTTP handlers passed to setupRouter.
+type handlers struct {
+    auth      *handler.AuthHandler
+    user      *handler.UserHandler
+    adminUser *handler.AdminUserHandler
+    attribute *handler.AttributeHandler
+    client    *handler.ClientHandler
+    config    *handler.ConfigHandler
+    flag      *handler.FlagHandler
+    health    *handler.HealthHandler
+    logLevel  *handler.LogLevelHandler
+}
+
+// serve runs the API until SIGINT or SIGTERM and returns the exit code,
+// which is non-zero when shutdown did not complete cleanly.
+func serve(reloader *config.Reloader, runMigrations bool) int {
+    cfg := reloader.Current()
 
     // Initialize logger
-    log := logger.New(cfg.LogLevel)
+    logControl := logger.NewControl(logger.ParseLevel(cfg.Log.Level))
+    logControl.SetSampling(logSampling(cfg.Log.Sampling))
+    logSinks, err := logger.OpenSinks(logSinkConfigs(cfg.Log.Sinks))
+    if err != nil {
+        fmt.Fprintln(os.Stderr, "Failed to open log sinks:", err)
+        return 1
+    }
+    defer logSinks.Close()
+    log := logger.NewWithSinks(logControl, logSinks)
+    logger.SetDefault(log)
+
+    // Initialize tracing before the database, whose queries it traces
+    shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+        Exporter:       cfg.Tracing.Exporter,
+        Endpoint:       cfg.Tracing.OTLPEndpoint,
+        Insecure:       cfg.Tracing.OTLPInsecure,
+        SampleRatio:    cfg.Tracing.SampleRatio,
+        ServiceName:    cfg.Tracing.ServiceName,
+        ServiceVersion: buildinfo.Get().Version,
+        Environment:    cfg.Environment,
+    })
+    if err != nil {
+        log.Fatal("Failed to set up tracing", err)
+    }
 
     // Initialize database
-    db, err := database.NewPostgresDB(cfg.DatabaseURL)
+    db, err := database.NewPostgresDB(cfg.DB.URL.Value(), database.PoolConfig{
+        MaxOpenConns:    cfg.DB.MaxOpenConns,
+        MaxIdleConns:    cfg.DB.MaxIdleConns,
+        ConnMaxLifetime: cfg.DB.ConnMaxLifetime.Duration,
+        ConnMaxIdleTime: cfg.DB.ConnMaxIdleTime.Duration,
+    }, database.QueryLogConfig{
+        SlowThreshold: cfg.DB.SlowQueryThreshold.Duration,
+        Explain:       cfg.DB.ExplainSlowQueries,
+    })
     if err != nil {
         log.Fatal("Failed to connect to database", err)
     }
     defer db.Close()
+    if cfg.Metrics.Enabled {
+        metrics.RegisterDB(db, "postgres")
+    }
+
+    // Redis only backs the shared rate limiter. The client connects on
+    // first use, so it is created whenever a URL is set and a reload can
+    // switch the limiter to Redis
+    var redisClient *redis.Client
+    if cfg.Redis.URL != "" {
+        opts, err := redis.ParseURL(cfg.Redis.URL.Value())
+        if err != nil {
+            log.Fatal("Invalid redis.url", err)
+        }
+        opts.DialTimeout = cfg.Redis.Timeout.Duration
+        opts.ReadTimeout = cfg.Redis.Timeout.Duration
+        opts.WriteTimeout = cfg.Redis.Timeout.Duration
+        redisClient = redis.NewClient(opts)
+        defer redisClient.Close()
+    }
+
+    // Replicas started together wait on an advisory lock, so only the first
+    // applies the migrations
+    if runMigrations {
+        log.Info("Applying database migrations")
+        if err := database.RunMigrations(context.Background(), db); err != nil {
+            log.Fatal("Failed to apply migrations", err)
+        }
+    }
+
+    // New code against an old schema fails on its first query with an
+    // obscure SQL error, so check the schema before serving anything
+    schema, err := database.GetMigrationStatus(context.Background(), db)
+    if err != nil {
+        log.Fatal("Failed to read database schema version", err)
+    }
+    if problem := schema.Problem(); problem != "" {
+        if cfg.DB.SchemaCheck == config.SchemaCheckFail {
+            log.Fatal("Database schema does not match this build", errors.New(problem))
+        }
+        log.Warn("Database schema does not match this build", "problem", problem, "schema_check", cfg.DB.SchemaCheck)
+    } else {
+        log.Info("Database schema is current", "version", schema.Version)
+    }
 
     // Initialize repositories
+    txManager := postgres.NewTxManager(db, nil)
     userRepo := postgres.NewUserRepository(db)
+    roleRepo := postgres.NewRoleRepository(db)
+    jobRepo := postgres.NewJobRepository(db)
+    attrRepo := postgres.NewAttributeRepository(db)
+    flagRepo := postgres.NewFlagRepository(db)
 
     // Initialize services
-    authService := service.NewAuthService(userRepo, cfg.JWTSecret)
-    userService := service.NewUserService(userRepo)
+    mail := mailer.NewLogMailer(log.With("module", "mailer"))
+    if cfg.Mail.SMTPHost != "" {
+        mail = mailer.NewSMTPMailer(mailer.SMTPConfig{
+            Host:     cfg.Mail.SMTPHost,
+            Port:     cfg.Mail.SMTPPort,
+            Username: cfg.Mail.SMTPUsername,
+            Password: cfg.Mail.SMTPPassword.Value(),
+            From:     cfg.Mail.From,
+        })
+    }
+    tokens := service.TokenConfig{
+        Secret:     cfg.Auth.JWTSecret.Value(),
+        AccessTTL:  cfg.Auth.AccessTokenTTL.Duration,
+        RefreshTTL: cfg.Auth.RefreshTokenTTL.Duration,
+    }
+    legacyHash := utils.LegacyHashParams{
+        Firebase: utils.FirebaseScryptParams{
+            SignerKey:     cfg.Auth.Firebase.SignerKey.Value(),
+            SaltSeparator: cfg.Auth.Firebase.SaltSeparator,
+            Rounds:        cfg.Auth.Firebase.Rounds,
+            MemCost:       cfg.Auth.Firebase.MemCost,
+        },
+    }
+    attributeService := service.NewAttributeService(attrRepo)
+    authService := service.NewAuthService(txManager, userRepo, roleRepo, attributeService, tokens, legacyHash)
+    userService := service.NewUserService(userRepo, attributeService)
+    importService := service.NewUserImportService(txManager, userRepo, roleRepo, jobRepo, attributeService, mail)
+    if n, err := importService.FailStaleJobs(context.Background()); err != nil {
+        log.Error("Failed to clean up stale jobs", err)
+    } else if n > 0 {
+        log.Warn("Marked stale jobs as failed", "count", n)
+    }
+    flagService := service.NewFlagService(flagRepo, cfg.Features)
+
+    // Dependency checks behind /readyz and /health
+    checks := health.NewRegistry()
+    checks.Register(health.Check{
+        Name:     "postgres",
+        Timeout:  2 * time.Second,
+        Critical: true,
+        Check:    db.PingContext,
+    })
+    if cfg.DB.SchemaCheck != config.SchemaCheckOff {
+        checks.Register(health.Check{
+            Name:     "schema",
+            Timeout:  2 * time.Second,
+            Critical: true,
+            Check: func(ctx context.Context) error {
+                schema, err := database.GetMigrationStatus(ctx, db)
+                if err != nil {
+                    return err
+                }
+                if problem := schema.Problem(); problem != "" {
+                    return errors.New(problem)
+                }
+                return nil
+            },
+        })
+    }
+    if pinger, ok := mail.(mailer.Pinger); ok {
+        // Mail is sent from background jobs, so an unreachable relay does
+        // not stop the API serving
+        checks.Register(health.Check{
+            Name:    "smtp",
+            Timeout: 3 * time.Second,
+            Check:   pinger.Ping,
+        })
+    }
+
+    if redisClient != nil && cfg.RateLimit.Enabled && cfg.RateLimit.Backend == config.RateLimitBackendRedis {
+        // The rate limiter falls back to local limits without Redis
+        checks.Register(health.Check{
+            Name:    "redis",
+            Timeout: time.Second,
+            Check: func(ctx context.Context) error {
+                return redisClient.Ping(ctx).Err()
+            },
+        })
+    }
+
+    // Load feature flags and keep them in step with changes made by any instance
+    flagLog := log.With("module", "flags")
+    listenCtx, stopListening := context.WithCancel(context.Background())
+    defer stopListening()
+    if err := flagService.Refresh(listenCtx); err != nil {
+        flagLog.Error("Failed to load feature flags", err)
+    }
+    err = database.Listen(listenCtx, cfg.DB.URL.Value(), "feature_flags", func(string) {
+        if err := flagService.Refresh(listenCtx); err != nil {
+            flagLog.Error("Failed to refresh feature flags", err)
+        }
+    })
+    if err != nil {
+        flagLog.Error("Feature flag changes from other instances will not be seen", err)
+    }
 
     // Initialize handlers
-    authHandler := handler.NewAuthHandler(authService, log)
-    userHandler := handler.NewUserHandler(userService, log)
-    healthHandler := handler.NewHealthHandler(db, log)
+    h := handlers{
+        auth:      handler.NewAuthHandler(authService),
+        user:      handler.NewUserHandler(userService),
+        adminUser: handler.NewAdminUserHandler(importService, userService),
+        attribute: handler.NewAttributeHandler(attributeService),
+        client:    handler.NewClientHandler(authService, userService),
+        config:    handler.NewConfigHandler(reloader),
+        flag:      handler.NewFlagHandler(flagService),
+        health:    handler.NewHealthHandler(checks, db),
+        logLevel:  handler.NewLogLevelHandler(logControl),
+    }
+
+    // Reloadable middleware follows the config on SIGHUP or admin reload
+    corsPolicy := middleware.NewCORSPolicy(cfg.CORS)
+    rateLimitPolicy := middleware.NewRateLimitPolicy(cfg.RateLimit, redisClient)
+    accessLogPolicy := middleware.NewAccessLogPolicy(cfg.Log.Access)
+    reloader.OnReload(func(next *config.Config) {
+        logControl.SetLevel(logger.ParseLevel(next.Log.Level))
+        logControl.SetSampling(logSampling(next.Log.Sampling))
+        accessLogPolicy.Update(next.Log.Access)
+        corsPolicy.Update(next.CORS)
+        rateLimitPolicy.Update(next.RateLimit)
+        flagService.SetDefaults(next.Features)
+    })
 
     // Setup router
-    router := setupRouter(cfg, authHandler, userHandler, healthHandler)
+    router := setupRouter(cfg, h, flagService, corsPolicy, rateLimitPolicy, accessLogPolicy, log)
 
     // Setup server
     srv := &http.Server{
-        Addr:         fmt.Sprintf(":%s", cfg.Port),
+        Addr:         fmt.Sprintf(":%s", cfg.HTTP.Port),
         Handler:      router,
-        ReadTimeout:  15 * time.Second,
-        WriteTimeout: 15 * time.Second,
-        IdleTimeout:  60 * time.Second,
+        ReadTimeout:  cfg.HTTP.ReadTimeout.Duration,
+        WriteTimeout: cfg.HTTP.WriteTimeout.Duration,
+        IdleTimeout:  cfg.HTTP.IdleTimeout.Duration,
     }
 
     // Start server
     go func() {
-        log.Info(fmt.Sprintf("Server starting on port %s", cfg.Port))
+        log.Info(fmt.Sprintf("Server starting on port %s", cfg.HTTP.Port), "environment", cfg.Environment)
         if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
             log.Fatal("Failed to start server", err)
         }
     }()
 
+    // Metrics on their own port stay off the public listener
+    var metricsSrv *http.Server
+    if cfg.Metrics.Enabled && cfg.Metrics.Port != "" {
+        mux := http.NewServeMux()
+        mux.Handle("/metrics", metrics.Handler())
+        metricsSrv = &http.Server{
+            Addr:              fmt.Sprintf(":%s", cfg.Metrics.Port),
+            Handler:           mux,
+            ReadHeaderTimeout: cfg.HTTP.ReadTimeout.Duration,
+        }
+        go func() {
+            log.Info(fmt.Sprintf("Metrics listening on port %s", cfg.Metrics.Port))
+            if err := metricsSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+                log.Error("Metrics listener failed", err)
+            }
+        }()
+    }
+
+    // Profiling and runtime state on an internal listener. No write
+    // timeout: CPU profiles and traces stream for as long as asked
+    var debugSrv *http.Server
+    if cfg.Debug.Enabled {
+        debugSrv = &http.Server{
+            Addr: cfg.Debug.Addr,
+            Handler: debugserver.Handler(debugserver.Options{
+                Token:     cfg.Debug.Token.Value(),
+                Config:    reloader,
+                DB:        db,
+                RateLimit: rateLimitPolicy,
+                Log:       log.With("module", "debug"),
+            }),
+            ReadHeaderTimeout: cfg.HTTP.ReadTimeout.Duration,
+        }
+        go func() {
+            log.Info(fmt.Sprintf("Debug listener on %s", cfg.Debug.Addr))
+            if err := debugSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+                log.Error("Debug listener failed", err)
+            }
+        }()
+    }
+
+    // Reload the config on SIGHUP
+    hup := make(chan os.Signal, 1)
+    signal.Notify(hup, syscall.SIGHUP)
+    go func() {
+        for range hup {
+            result, err := reloader.Reload()
+            if err != nil {
+                log.Error("Config reload rejected", err)
+                continue
+            }
+            log.Info("Config reloaded", "changed", result.Changed, "restart_required", result.RestartRequired)
+        }
+    }()
+
+    // SIGUSR1 turns on debug logging for a while, SIGUSR2 turns it off again
+    usr := make(chan os.Signal, 1)
+    signal.Notify(usr, syscall.SIGUSR1, syscall.SIGUSR2)
+    go func() {
+        for sig := range usr {
+            if sig == syscall.SIGUSR2 {
+                logControl.ClearOverrides()
+                log.Info("Log level overrides cleared")
+                continue
+            }
+            logControl.SetOverride(logger.Override{Level: "debug", ExpiresAt: time.Now().Add(signalDebugTTL)})
+            log.Info("Debug logging enabled", "ttl", signalDebugTTL.String())
+        }
+    }()
+
     // Wait for interrupt signal to gracefully shutdown
     quit := make(chan os.Signal, 1)
     signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
     <-quit
     log.Info("Shutting down server...")
 
-    // Graceful shutdown
-    ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+    // Fail readiness first, so load balancers stop sending new requests
+    // while the ones in flight finish
+    checks.SetShuttingDown()
+    if delay := cfg.HTTP.ShutdownDelay.Duration; delay > 0 {
+        log.Info("Waiting for load balancers to drain", "delay", delay.String())
+        time.Sleep(delay)
+    }
+
+    // Graceful shutdown. A step that fails is logged and the rest still
+    // run, so the listeners close and buffered spans are flushed anyway
+    ctx, cancel := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout.Duration)
     defer cancel()
 
+    code := 0
     if err := srv.Shutdown(ctx); err != nil {
-        log.Fatal("Server forced to shutdown", err)
+        log.Error("Server forced to shutdown", err)
+        code = 1
+    }
+    if metricsSrv != nil {
+        if err := metricsSrv.Shutdown(ctx); err != nil {
+            log.Error("Metrics listener forced to shutdown", err)
+            code = 1
+        }
+    }
+    if debugSrv != nil {
+        if err := debugSrv.Shutdown(ctx); err != nil {
+            log.Error("Debug listener forced to shutdown", err)
+            code = 1
+        }
+    }
+    // No new imports can start now; those running get what is left of the
+    // timeout, then are marked failed
+    if err := importService.Shutdown(ctx); err != nil {
+        log.Error("Imports interrupted by shutdown", err)
+        code = 1
+    }
+
+    // The shutdown timeout may be used up by now
+    flushCtx, cancelFlush := context.WithTimeout(context.Background(), tracingFlushTimeout)
+    defer cancelFlush()
+    if err := shutdownTracing(flushCtx); err != nil {
+        log.Error("Failed to flush traces", err)
+        code = 1
     }
 
-    log.Info("Server exited")
+    log.Info("Server exited", "exit_code", code)
+    return code
 }
 
-func setupRouter(cfg *config.Config, authHandler *handler.AuthHandler, userHandler *handler.UserHandler, healthHandler *handler.HealthHandler) *gin.Engine {
-    if cfg.Environment == "production" {
+func setupRouter(cfg *config.Config, h handlers, flags *service.FlagService, corsPolicy *middleware.CORSPolicy, rateLimitPolicy *middleware.RateLimitPolicy, accessLogPolicy *middleware.AccessLogPolicy, log logger.Logger) *gin.Engine {
+    if cfg.IsProduction() {
         gin.SetMode(gin.ReleaseMode)
     }
 
     r := gin.New()
 
     // Global middleware
-    r.Use(middleware.Logger())
-    r.Use(middleware.CORS())
-    // r.Use(middleware.RateLimit())
+    r.Use(middleware.RequestID(log.With("module", "http")))
+    r.Use(middleware.Tracing())
+    r.Use(accessLogPolicy.Handler())
+    r.Use(middleware.QueryStats(cfg.DB.NPlusOneThreshold))
+    if cfg.Metrics.Enabled {
+        r.Use(middleware.Metrics())
+    }
+    r.Use(corsPolicy.Handler())
+    r.Use(rateLimitPolicy.Handler())
     r.Use(gin.Recovery())
 
-    // Health check
-    r.GET("/health", healthHandler.HealthCheck)
+    // Probes
+    r.GET("/livez", h.health.Liveness)
+    r.GET("/readyz", h.health.Readiness)
+    r.GET("/health", h.health.HealthCheck)
+
+    if cfg.Metrics.Enabled && cfg.Metrics.Port == "" {
+        r.GET("/metrics", gin.WrapH(metrics.Handler()))
+    }
+
+    // Routes used by the bundled React client
+    r.POST("/login", h.client.Login)
+    r.GET("/user/profile", middleware.AuthMiddleware(cfg.Auth.JWTSecret.Value()), h.client.GetProfile)
 
     // API routes
     api := r.Group("/api/v1")
@@ -104,24 +479,83 @@ func setupRouter(cfg *config.Config, authHandler *handler.AuthHandler, userHandl
         // Auth routes
         auth := api.Group("/auth")
         {
-            auth.POST("/register", authHandler.Register)
-            auth.POST("/login", authHandler.Login)
-            auth.POST("/refresh", authHandler.RefreshToken)
+            auth.POST("/register", h.auth.Register)
+            auth.POST("/login", h.auth.Login)
+            auth.POST("/refresh", h.auth.RefreshToken)
         }
 
         // Protected routes
         protected := api.Group("/")
-        protected.Use(middleware.AuthMiddleware(cfg.JWTSecret))
+        protected.Use(middleware.AuthMiddleware(cfg.Auth.JWTSecret.Value()))
         {
             // User routes
             users := protected.Group("/users")
             {
-                users.GET("/profile", userHandler.GetProfile)
-                users.PUT("/profile", userHandler.UpdateProfile)
-                users.GET("", userHandler.GetUsers)
+                users.GET("/profile", h.user.GetProfile)
+                users.PUT("/profile", h.user.UpdateProfile)
+                users.GET("", h.user.GetUsers)
+                users.GET("/search", middleware.RequireRole(model.RoleAdmin), h.user.SearchUsers)
+            }
+
+            // Feature flags as seen by the caller
+            protected.GET("/flags", h.flag.GetMyFlags)
+
+            // Admin routes
+            admin := protected.Group("/admin")
+            admin.Use(middleware.RequireRole(model.RoleAdmin))
+            {
+                admin.POST("/users/import", middleware.RequireFlag(flags, model.FlagUserImport), h.adminUser.ImportUsers)
+                admin.GET("/users/export", h.adminUser.ExportUsers)
+                admin.GET("/jobs/:id", h.adminUser.GetJob)
+
+                admin.GET("/attributes", h.attribute.ListAttributes)
+                admin.POST("/attributes", h.attribute.CreateAttribute)
+                admin.PUT("/attributes/:key", h.attribute.UpdateAttribute)
+                admin.DELETE("/attributes/:key", h.attribute.DeleteAttribute)
+
+                admin.GET("/flags", h.flag.ListFlags)
+                admin.POST("/flags", h.flag.CreateFlag)
+                admin.GET("/flags/:name", h.flag.GetFlag)
+                admin.PUT("/flags/:name", h.flag.UpdateFlag)
+                admin.DELETE("/flags/:name", h.flag.DeleteFlag)
+
+                admin.POST("/config/reload", h.config.ReloadConfig)
+
+                admin.GET("/log-level", h.logLevel.GetLogLevel)
+                admin.PUT("/log-level", h.logLevel.SetLogLevel)
+                admin.DELETE("/log-level", h.logLevel.ResetLogLevel)
             }
         }
     }
 
     return r
+}
+
+func logSampling(cfg config.LogSamplingConfig) logger.Sampling {
+    return logger.Sampling{
+        First:      cfg.First,
+        Thereafter: cfg.Thereafter,
+        Period:     cfg.Period.Duration,
+    }
+}
+
+func logSinkConfigs(sinks []config.LogSinkConfig) []logger.SinkConfig {
+    configs := make([]logger.SinkConfig, len(sinks))
+    for i, sink := range sinks {
+        configs[i] = logger.SinkConfig{
+            Type:       sink.Type,
+            Format:     sink.Format,
+            Level:      sink.Level,
+            Path:       sink.Path,
+            MaxSizeMB:  sink.MaxSizeMB,
+            MaxAge:     sink.MaxAge.Duration,
+            MaxBackups: sink.MaxBackups,
+            Compress:   sink.Compress,
+            Network:    sink.Network,
+            Address:    sink.Address,
+            Facility:   sink.Facility,
+            AppName:    sink.AppName,
+        }
+    }
+    return configs
 }
\ No newline at end of file
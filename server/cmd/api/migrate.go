@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/francis/projectx-api/internal/config"
+	"github.com/francis/projectx-api/internal/database"
+)
+
+const migrateUsage = `Usage: api migrate <command> [--config file] [args]
+
+Commands:
+  up              apply every pending migration
+  down [N]        roll back N migrations (default 1)
+  status          show the applied and the latest embedded version
+  force VERSION   mark VERSION as applied and clear the dirty flag, without
+                  running anything; use after repairing a failed migration
+  create NAME     write empty up and down files for a new migration into
+                  --dir (default "migrations")`
+
+var migrationNamePattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// runMigrate implements the "migrate" subcommand and returns the exit code.
+func runMigrate(args []string) int {
+    if len(args) == 0 {
+        fmt.Fprintln(os.Stderr, migrateUsage)
+        return 2
+    }
+    command := args[0]
+
+    flags := flag.NewFlagSet("migrate "+command, flag.ExitOnError)
+    configPath := flags.String("config", "", "YAML or TOML config file (default $CONFIG_FILE)")
+    dir := flags.String("dir", "migrations", "directory for new migration files (create only)")
+    flags.Usage = func() { fmt.Fprintln(os.Stderr, migrateUsage) }
+    rest := parseInterspersed(flags, args[1:])
+
+    switch command {
+    case "up", "down", "status", "force":
+    case "create":
+        if len(rest) != 1 {
+            fmt.Fprintln(os.Stderr, migrateUsage)
+            return 2
+        }
+        return exitCode(createMigration(*dir, rest[0]))
+    default:
+        fmt.Fprintln(os.Stderr, migrateUsage)
+        return 2
+    }
+
+    // Check arguments before connecting to the database
+    steps, version := 1, 0
+    switch {
+    case command == "down" && len(rest) > 0:
+        n, err := strconv.Atoi(rest[0])
+        if err != nil || n < 1 {
+            fmt.Fprintln(os.Stderr, "down takes a positive number of migrations")
+            return 2
+        }
+        steps = n
+    case command == "force":
+        if len(rest) != 1 {
+            fmt.Fprintln(os.Stderr, migrateUsage)
+            return 2
+        }
+        n, err := strconv.Atoi(rest[0])
+        if err != nil {
+            fmt.Fprintln(os.Stderr, "force takes a migration version")
+            return 2
+        }
+        version = n
+    }
+
+    cfg, err := config.Read(*configPath)
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        return 1
+    }
+    db, err := database.NewPostgresDB(cfg.DB.URL.Value(), database.DefaultPoolConfig, database.QueryLogConfig{})
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        return 1
+    }
+    defer db.Close()
+
+    ctx := context.Background()
+    switch command {
+    case "up":
+        err = database.RunMigrations(ctx, db)
+    case "down":
+        err = database.MigrateDown(ctx, db, steps)
+    case "force":
+        err = database.ForceMigrationVersion(ctx, db, version)
+    case "status":
+        var status *database.MigrationStatus
+        if status, err = database.GetMigrationStatus(ctx, db); err == nil {
+            fmt.Printf("version: %d\ndirty:   %t\nlatest:  %d\npending: %d\n",
+                status.Version, status.Dirty, status.Latest, status.Pending)
+        }
+    }
+
+    if err == nil && command != "status" {
+        status, statusErr := database.GetMigrationStatus(ctx, db)
+        if statusErr == nil {
+            fmt.Printf("database is at version %d of %d\n", status.Version, status.Latest)
+        }
+    }
+    return exitCode(err)
+}
+
+// createMigration writes NNN_name.up.sql and NNN_name.down.sql, numbered
+// after the highest migration already in dir or embedded in the binary.
+func createMigration(dir, name string) error {
+    name = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(name), " ", "_"))
+    if !migrationNamePattern.MatchString(name) {
+        return fmt.Errorf("migration name must contain only letters, digits and underscores")
+    }
+
+    var next uint = 1
+    versions, err := database.EmbeddedMigrations()
+    if err != nil {
+        return err
+    }
+    if len(versions) > 0 {
+        next = versions[len(versions)-1] + 1
+    }
+
+    existing, err := filepath.Glob(filepath.Join(dir, "*.up.sql"))
+    if err != nil {
+        return err
+    }
+    for _, path := range existing {
+        prefix, _, _ := strings.Cut(filepath.Base(path), "_")
+        if v, err := strconv.ParseUint(prefix, 10, 64); err == nil && uint(v) >= next {
+            next = uint(v) + 1
+        }
+    }
+
+    for _, direction := range []string{"up", "down"} {
+        path := filepath.Join(dir, fmt.Sprintf("%03d_%s.%s.sql", next, name, direction))
+        f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+        if err != nil {
+            return err
+        }
+        f.Close()
+        fmt.Println(path)
+    }
+    return nil
+}
+
+// parseInterspersed parses flags that may come before, between or after
+// positional arguments, which the flag package alone stops at, and returns
+// the positional arguments.
+func parseInterspersed(flags *flag.FlagSet, args []string) []string {
+    var positional []string
+    for {
+        flags.Parse(args)
+        args = flags.Args()
+        if len(args) == 0 {
+            return positional
+        }
+        positional = append(positional, args[0])
+        args = args[1:]
+    }
+}
+
+func exitCode(err error) int {
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        return 1
+    }
+    return 0
+}
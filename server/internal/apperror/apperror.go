@@ -0,0 +1,195 @@
+// Package apperror defines the errors repositories and services return for
+// failures the caller can act on. Each error has a Kind, which the handlers
+// map to an HTTP status; anything without a Kind is an internal error.
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+type Kind int
+
+const (
+    KindInternal Kind = iota
+    KindNotFound
+    KindConflict
+    KindValidation
+    KindUnauthorized
+    KindForbidden
+    KindRateLimited
+)
+
+func (k Kind) String() string {
+    switch k {
+    case KindNotFound:
+        return "not_found"
+    case KindConflict:
+        return "conflict"
+    case KindValidation:
+        return "validation"
+    case KindUnauthorized:
+        return "unauthorized"
+    case KindForbidden:
+        return "forbidden"
+    case KindRateLimited:
+        return "rate_limited"
+    }
+    return "internal_error"
+}
+
+// Status returns the HTTP status for errors of kind k.
+func (k Kind) Status() int {
+    switch k {
+    case KindNotFound:
+        return http.StatusNotFound
+    case KindConflict:
+        return http.StatusConflict
+    case KindValidation:
+        return http.StatusBadRequest
+    case KindUnauthorized:
+        return http.StatusUnauthorized
+    case KindForbidden:
+        return http.StatusForbidden
+    case KindRateLimited:
+        return http.StatusTooManyRequests
+    }
+    return http.StatusInternalServerError
+}
+
+// Error is a failure of a known kind. Message is safe to show to clients;
+// Err, when set, is the underlying cause and is only logged. Code is a
+// stable identifier clients can branch on instead of the message, which
+// may be reworded or translated. Status, when set, replaces the status of
+// the kind, and Details is sent to the client alongside the message.
+type Error struct {
+    Kind    Kind
+    Code    string
+    Message string
+    Err     error
+    Status  int
+    Details interface{}
+}
+
+// WithCode sets the code of a newly created error and returns it.
+func (e *Error) WithCode(code string) *Error {
+    e.Code = code
+    return e
+}
+
+// WithStatus sets the HTTP status of a newly created error and returns it.
+func (e *Error) WithStatus(status int) *Error {
+    e.Status = status
+    return e
+}
+
+// WithDetails attaches data for the client, such as a per-row report, to a
+// newly created error and returns it.
+func (e *Error) WithDetails(details interface{}) *Error {
+    e.Details = details
+    return e
+}
+
+// HTTPStatus returns the status set on e, or the status of its kind.
+func (e *Error) HTTPStatus() int {
+    if e.Status != 0 {
+        return e.Status
+    }
+    return e.Kind.Status()
+}
+
+// ErrorCode returns the code of e, or the name of its kind when no more
+// specific code was set.
+func (e *Error) ErrorCode() string {
+    if e.Code != "" {
+        return e.Code
+    }
+    return e.Kind.String()
+}
+
+func (e *Error) Error() string {
+    if e.Err != nil && e.Message == "" {
+        return e.Err.Error()
+    }
+    return e.Message
+}
+
+func (e *Error) Unwrap() error {
+    return e.Err
+}
+
+// Is makes the sentinels below match every error of their kind, so
+// errors.Is(err, apperror.ErrNotFound) works whatever the message.
+func (e *Error) Is(target error) bool {
+    t, ok := target.(*Error)
+    return ok && t.Message == "" && t.Code == "" && t.Err == nil && t.Kind == e.Kind
+}
+
+// Sentinels for use with errors.Is.
+var (
+    ErrNotFound     = &Error{Kind: KindNotFound}
+    ErrConflict     = &Error{Kind: KindConflict}
+    ErrValidation   = &Error{Kind: KindValidation}
+    ErrUnauthorized = &Error{Kind: KindUnauthorized}
+    ErrForbidden    = &Error{Kind: KindForbidden}
+    ErrRateLimited  = &Error{Kind: KindRateLimited}
+)
+
+func New(kind Kind, format string, args ...interface{}) *Error {
+    return &Error{Kind: kind, Message: fmt.Sprintf(format, args...)}
+}
+
+// Wrap gives err a kind. With an empty format the message is err's own.
+func Wrap(err error, kind Kind, format string, args ...interface{}) *Error {
+    e := &Error{Kind: kind, Err: err}
+    if format != "" {
+        e.Message = fmt.Sprintf(format, args...)
+    } else {
+        e.Message = err.Error()
+    }
+    return e
+}
+
+func NotFound(format string, args ...interface{}) *Error {
+    return New(KindNotFound, format, args...)
+}
+
+func Conflict(format string, args ...interface{}) *Error {
+    return New(KindConflict, format, args...)
+}
+
+func Validation(format string, args ...interface{}) *Error {
+    return New(KindValidation, format, args...)
+}
+
+func Unauthorized(format string, args ...interface{}) *Error {
+    return New(KindUnauthorized, format, args...)
+}
+
+func Forbidden(format string, args ...interface{}) *Error {
+    return New(KindForbidden, format, args...)
+}
+
+func RateLimited(format string, args ...interface{}) *Error {
+    return New(KindRateLimited, format, args...)
+}
+
+// Invalid marks err, typically from the validator package, as a
+// validation failure.
+func Invalid(err error) error {
+    if err == nil {
+        return nil
+    }
+    return Wrap(err, KindValidation, "").WithCode("validation_failed")
+}
+
+// KindOf returns the kind of the first Error in err's chain, or
+// KindInternal if there is none.
+func KindOf(err error) Kind {
+    var e *Error
+    if errors.As(err, &e) {
+        return e.Kind
+    }
+    return KindInternal
+}
@@ -0,0 +1,167 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestKind(t *testing.T) {
+    tests := []struct {
+        kind   Kind
+        name   string
+        status int
+    }{
+        {KindInternal, "internal_error", http.StatusInternalServerError},
+        {KindNotFound, "not_found", http.StatusNotFound},
+        {KindConflict, "conflict", http.StatusConflict},
+        {KindValidation, "validation", http.StatusBadRequest},
+        {KindUnauthorized, "unauthorized", http.StatusUnauthorized},
+        {KindForbidden, "forbidden", http.StatusForbidden},
+        {KindRateLimited, "rate_limited", http.StatusTooManyRequests},
+        {Kind(99), "internal_error", http.StatusInternalServerError},
+    }
+
+    for _, tt := range tests {
+        if got := tt.kind.String(); got != tt.name {
+            t.Errorf("Kind(%d).String() = %q, want %q", tt.kind, got, tt.name)
+        }
+        if got := tt.kind.Status(); got != tt.status {
+            t.Errorf("Kind(%d).Status() = %d, want %d", tt.kind, got, tt.status)
+        }
+    }
+}
+
+func TestError(t *testing.T) {
+    cause := errors.New("pq: duplicate key value violates unique constraint")
+
+    tests := []struct {
+        name     string
+        err      error
+        kind     Kind
+        code     string
+        message  string
+        sentinel error
+    }{
+        {
+            name:     "constructor",
+            err:      NotFound("user %d not found", 7),
+            kind:     KindNotFound,
+            code:     "not_found",
+            message:  "user 7 not found",
+            sentinel: ErrNotFound,
+        },
+        {
+            name:     "with code",
+            err:      Conflict("email is already registered").WithCode("email_taken"),
+            kind:     KindConflict,
+            code:     "email_taken",
+            message:  "email is already registered",
+            sentinel: ErrConflict,
+        },
+        {
+            name:     "wrapped with message",
+            err:      Wrap(cause, KindConflict, "insert %s", "a@example.com"),
+            kind:     KindConflict,
+            code:     "conflict",
+            message:  "insert a@example.com",
+            sentinel: ErrConflict,
+        },
+        {
+            name:     "wrapped without message",
+            err:      Wrap(cause, KindForbidden, ""),
+            kind:     KindForbidden,
+            code:     "forbidden",
+            message:  cause.Error(),
+            sentinel: ErrForbidden,
+        },
+        {
+            name:     "invalid",
+            err:      Invalid(cause),
+            kind:     KindValidation,
+            code:     "validation_failed",
+            message:  cause.Error(),
+            sentinel: ErrValidation,
+        },
+        {
+            name:     "wrapped by fmt",
+            err:      fmt.Errorf("load profile: %w", RateLimited("slow down")),
+            kind:     KindRateLimited,
+            code:     "rate_limited",
+            message:  "slow down",
+            sentinel: ErrRateLimited,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := KindOf(tt.err); got != tt.kind {
+                t.Errorf("KindOf() = %s, want %s", got, tt.kind)
+            }
+
+            var appErr *Error
+            if !errors.As(tt.err, &appErr) {
+                t.Fatalf("errors.As(%v) = false, want an *Error", tt.err)
+            }
+            if got := appErr.ErrorCode(); got != tt.code {
+                t.Errorf("ErrorCode() = %q, want %q", got, tt.code)
+            }
+            if appErr.Error() != tt.message {
+                t.Errorf("Error() = %q, want %q", appErr.Error(), tt.message)
+            }
+
+            if !errors.Is(tt.err, tt.sentinel) {
+                t.Errorf("errors.Is(err, %s sentinel) = false, want true", tt.kind)
+            }
+            for _, other := range []error{ErrNotFound, ErrConflict, ErrValidation, ErrUnauthorized, ErrForbidden, ErrRateLimited} {
+                if other != tt.sentinel && errors.Is(tt.err, other) {
+                    t.Errorf("errors.Is(err, %v) = true, want only the %s sentinel to match", other.(*Error).Kind, tt.kind)
+                }
+            }
+        })
+    }
+}
+
+func TestErrorKeepsCause(t *testing.T) {
+    cause := errors.New("connection refused")
+    err := Wrap(cause, KindConflict, "")
+    if !errors.Is(err, cause) {
+        t.Error("errors.Is(Wrap(cause), cause) = false, want true")
+    }
+
+    // A specific error is not a sentinel for other errors of its kind
+    if errors.Is(ErrNotFound, NotFound("user not found")) {
+        t.Error("errors.Is(ErrNotFound, NotFound(...)) = true, want false")
+    }
+}
+
+func TestInvalidNil(t *testing.T) {
+    if err := Invalid(nil); err != nil {
+        t.Errorf("Invalid(nil) = %v, want nil", err)
+    }
+}
+
+func TestKindOfPlainError(t *testing.T) {
+    if got := KindOf(errors.New("boom")); got != KindInternal {
+        t.Errorf("KindOf(plain error) = %s, want %s", got, KindInternal)
+    }
+}
+
+func TestHTTPStatus(t *testing.T) {
+    tests := []struct {
+        name string
+        err  *Error
+        want int
+    }{
+        {"kind status", Validation("bad input"), http.StatusBadRequest},
+        {"overridden", Validation("rows failed").WithStatus(http.StatusUnprocessableEntity), http.StatusUnprocessableEntity},
+        {"internal", Wrap(errors.New("boom"), KindInternal, ""), http.StatusInternalServerError},
+    }
+
+    for _, tt := range tests {
+        if got := tt.err.HTTPStatus(); got != tt.want {
+            t.Errorf("%s: HTTPStatus() = %d, want %d", tt.name, got, tt.want)
+        }
+    }
+}
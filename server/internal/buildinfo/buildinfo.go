@@ -0,0 +1,82 @@
+// Package buildinfo reports the version of the running binary. Release
+// builds set Version, Commit and Date with -ldflags, for example
+//
+//	go build -ldflags "-X github.com/francis/projectx-api/internal/buildinfo.Version=v1.4.0"
+//
+// and anything left unset is taken from the VCS stamp Go embeds in binaries
+// built from a checkout.
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// Set with -ldflags -X.
+var (
+    Version string
+    Commit  string
+    Date    string
+)
+
+// Info describes the running binary.
+type Info struct {
+    Version   string `json:"version"`
+    Commit    string `json:"commit"`
+    Date      string `json:"date,omitempty"`
+    Modified  bool   `json:"modified,omitempty"`
+    GoVersion string `json:"go_version"`
+}
+
+var (
+    once sync.Once
+    info Info
+)
+
+// Get returns the build information, read once.
+func Get() Info {
+    once.Do(load)
+    return info
+}
+
+func load() {
+    info = Info{
+        Version:   Version,
+        Commit:    Commit,
+        Date:      Date,
+        GoVersion: runtime.Version(),
+    }
+
+    if bi, ok := debug.ReadBuildInfo(); ok {
+        readBuildInfo(bi)
+    }
+    if info.Version == "" {
+        info.Version = "dev"
+    }
+    if info.Commit == "" {
+        info.Commit = "unknown"
+    }
+}
+
+// readBuildInfo fills what ldflags left unset from the module version and
+// the VCS stamp.
+func readBuildInfo(bi *debug.BuildInfo) {
+    if info.Version == "" && bi.Main.Version != "(devel)" {
+        info.Version = bi.Main.Version
+    }
+    for _, s := range bi.Settings {
+        switch s.Key {
+        case "vcs.revision":
+            if info.Commit == "" {
+                info.Commit = s.Value
+            }
+        case "vcs.time":
+            if info.Date == "" {
+                info.Date = s.Value
+            }
+        case "vcs.modified":
+            info.Modified = s.Value == "true"
+        }
+    }
+}
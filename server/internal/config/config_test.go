@@ -0,0 +1,260 @@
+package config
+
+import (
+    "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+    "time"
+)
+
+// clearEnv blanks every variable the config reads, so settings in the
+// environment running the tests do not leak into them.
+func clearEnv(t *testing.T) {
+    t.Helper()
+
+    t.Setenv("CONFIG_FILE", "")
+    var walk func(reflect.Type)
+    walk = func(typ reflect.Type) {
+        for i := 0; i < typ.NumField(); i++ {
+            field := typ.Field(i)
+            if name := field.Tag.Get("env"); name != "" {
+                t.Setenv(name, "")
+                t.Setenv(name+"_FILE", "")
+            } else if field.Type.Kind() == reflect.Struct {
+                walk(field.Type)
+            }
+        }
+    }
+    walk(reflect.TypeOf(Config{}))
+}
+
+func writeFile(t *testing.T, name, content string) string {
+    t.Helper()
+
+    path := filepath.Join(t.TempDir(), name)
+    if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+        t.Fatal(err)
+    }
+    return path
+}
+
+func TestReadLayers(t *testing.T) {
+    tests := []struct {
+        name  string
+        file  string
+        body  string
+        env   map[string]string
+        check func(t *testing.T, cfg *Config)
+    }{
+        {
+            name: "defaults",
+            check: func(t *testing.T, cfg *Config) {
+                if !reflect.DeepEqual(cfg, Default()) {
+                    t.Errorf("Read(\"\") = %#v, want the defaults", cfg)
+                }
+            },
+        },
+        {
+            name: "yaml file",
+            file: "config.yaml",
+            body: "http:\n  port: \"9090\"\n  read_timeout: 2m\nratelimit:\n  window: 30\n",
+            check: func(t *testing.T, cfg *Config) {
+                if cfg.HTTP.Port != "9090" {
+                    t.Errorf("http.port = %q, want 9090", cfg.HTTP.Port)
+                }
+                if cfg.HTTP.ReadTimeout.Duration != 2*time.Minute {
+                    t.Errorf("http.read_timeout = %s, want 2m", cfg.HTTP.ReadTimeout)
+                }
+                if cfg.RateLimit.Window.Duration != 30*time.Second {
+                    t.Errorf("ratelimit.window = %s, want a bare number read as seconds", cfg.RateLimit.Window)
+                }
+                if cfg.HTTP.WriteTimeout != Default().HTTP.WriteTimeout {
+                    t.Errorf("http.write_timeout = %s, want the default kept", cfg.HTTP.WriteTimeout)
+                }
+            },
+        },
+        {
+            name: "toml file",
+            file: "config.toml",
+            body: "[cors]\nallowed_origins = [\"https://a.example\", \"https://b.example\"]\n",
+            check: func(t *testing.T, cfg *Config) {
+                want := []string{"https://a.example", "https://b.example"}
+                if !reflect.DeepEqual(cfg.CORS.AllowedOrigins, want) {
+                    t.Errorf("cors.allowed_origins = %q, want %q", cfg.CORS.AllowedOrigins, want)
+                }
+            },
+        },
+        {
+            name: "environment overrides the file",
+            file: "config.yaml",
+            body: "http:\n  port: \"9090\"\nlog:\n  level: warn\n",
+            env: map[string]string{
+                "PORT":            "7070",
+                "ALLOWED_ORIGINS": "https://a.example, ,https://b.example",
+                "METRICS_ENABLED": "true",
+            },
+            check: func(t *testing.T, cfg *Config) {
+                if cfg.HTTP.Port != "7070" {
+                    t.Errorf("http.port = %q, want PORT to win", cfg.HTTP.Port)
+                }
+                if cfg.Log.Level != "warn" {
+                    t.Errorf("log.level = %q, want the file value", cfg.Log.Level)
+                }
+                want := []string{"https://a.example", "https://b.example"}
+                if !reflect.DeepEqual(cfg.CORS.AllowedOrigins, want) {
+                    t.Errorf("cors.allowed_origins = %q, want %q", cfg.CORS.AllowedOrigins, want)
+                }
+                if !cfg.Metrics.Enabled {
+                    t.Error("metrics.enabled = false, want true")
+                }
+            },
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            clearEnv(t)
+            for name, value := range tt.env {
+                t.Setenv(name, value)
+            }
+            var path string
+            if tt.file != "" {
+                path = writeFile(t, tt.file, tt.body)
+            }
+
+            cfg, err := Read(path)
+            if err != nil {
+                t.Fatalf("Read: %v", err)
+            }
+            tt.check(t, cfg)
+        })
+    }
+}
+
+func TestReadErrors(t *testing.T) {
+    tests := []struct {
+        name    string
+        file    string
+        body    string
+        env     map[string]string
+        wantErr string
+    }{
+        {name: "unknown yaml field", file: "config.yaml", body: "http:\n  prot: \"80\"\n", wantErr: "field prot not found"},
+        {name: "unknown toml field", file: "config.toml", body: "[http]\nprot = \"80\"\n", wantErr: "parse"},
+        {name: "unsupported extension", file: "config.json", body: "{}", wantErr: "must be .yaml, .yml or .toml"},
+        {name: "bad duration", file: "config.yaml", body: "http:\n  read_timeout: soon\n", wantErr: `invalid duration "soon"`},
+        {name: "bad env integer", env: map[string]string{"DB_MAX_OPEN_CONNS": "many"}, wantErr: `DB_MAX_OPEN_CONNS: invalid integer "many"`},
+        {name: "bad env boolean", env: map[string]string{"METRICS_ENABLED": "sure"}, wantErr: `METRICS_ENABLED: invalid boolean "sure"`},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            clearEnv(t)
+            for name, value := range tt.env {
+                t.Setenv(name, value)
+            }
+            var path string
+            if tt.file != "" {
+                path = writeFile(t, tt.file, tt.body)
+            }
+
+            _, err := Read(path)
+            if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+                t.Errorf("Read() error = %v, want it to contain %q", err, tt.wantErr)
+            }
+        })
+    }
+}
+
+func TestValidate(t *testing.T) {
+    production := func(c *Config) {
+        c.Environment = EnvProduction
+        c.Auth.JWTSecret = Secret(strings.Repeat("s", minJWTSecretLength))
+        c.DB.URL = "postgres://app:pw@db.internal/app"
+        c.CORS.AllowedOrigins = []string{"https://app.example"}
+    }
+
+    tests := []struct {
+        name    string
+        modify  func(c *Config)
+        wantErr []string
+    }{
+        {name: "defaults", modify: func(c *Config) {}},
+        {name: "production", modify: production},
+        {
+            name: "several problems at once",
+            modify: func(c *Config) {
+                c.HTTP.Port = "http"
+                c.DB.MaxIdleConns = c.DB.MaxOpenConns + 1
+                c.Log.Level = "trace"
+            },
+            wantErr: []string{
+                `http.port must be a TCP port, got "http"`,
+                "db.max_idle_conns must not exceed db.max_open_conns",
+                "log.level must be one of debug, info, warn, error",
+            },
+        },
+        {
+            name: "production keeps development defaults",
+            modify: func(c *Config) {
+                c.Environment = EnvProduction
+                c.CORS.AllowedOrigins = []string{"*"}
+            },
+            wantErr: []string{
+                "auth.jwt_secret (JWT_SECRET) must be set in production",
+                "db.url (DATABASE_URL) must be set in production",
+                "cors.allowed_origins (ALLOWED_ORIGINS) must list explicit origins in production",
+            },
+        },
+        {
+            name: "production short jwt secret",
+            modify: func(c *Config) {
+                production(c)
+                c.Auth.JWTSecret = "short"
+            },
+            wantErr: []string{"auth.jwt_secret must be at least 32 characters in production"},
+        },
+        {
+            name: "credentials with any origin",
+            modify: func(c *Config) {
+                c.CORS.AllowedOrigins = []string{"*"}
+                c.CORS.AllowCredentials = true
+            },
+            wantErr: []string{`cors.allow_credentials cannot be used with the "*" origin`},
+        },
+        {
+            name: "redis backend without redis",
+            modify: func(c *Config) {
+                c.RateLimit.Enabled = true
+                c.RateLimit.Backend = RateLimitBackendRedis
+                c.Redis.URL = ""
+            },
+            wantErr: []string{"redis.url must be set for the redis rate limit backend"},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            cfg := Default()
+            tt.modify(cfg)
+
+            err := cfg.Validate()
+            if len(tt.wantErr) == 0 {
+                if err != nil {
+                    t.Fatalf("Validate() = %v, want nil", err)
+                }
+                return
+            }
+            if err == nil {
+                t.Fatalf("Validate() = nil, want errors %q", tt.wantErr)
+            }
+            for _, want := range tt.wantErr {
+                if !strings.Contains(err.Error(), want) {
+                    t.Errorf("Validate() = %v, want it to contain %q", err, want)
+                }
+            }
+        })
+    }
+}
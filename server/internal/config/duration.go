@@ -0,0 +1,33 @@
+package config
+
+import (
+    "fmt"
+    "strconv"
+    "time"
+)
+
+// Duration is a time.Duration that reads from text such as "15s" or "24h" in
+// config files and environment variables. A bare number is taken as seconds,
+// which keeps values like RATE_LIMIT_WINDOW=60 working.
+type Duration struct {
+    time.Duration
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+    s := string(text)
+    if secs, err := strconv.ParseInt(s, 10, 64); err == nil {
+        d.Duration = time.Duration(secs) * time.Second
+        return nil
+    }
+
+    parsed, err := time.ParseDuration(s)
+    if err != nil {
+        return fmt.Errorf("invalid duration %q", s)
+    }
+    d.Duration = parsed
+    return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+    return []byte(d.Duration.String()), nil
+}
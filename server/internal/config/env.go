@@ -0,0 +1,109 @@
+package config
+
+import (
+    "encoding"
+    "fmt"
+    "os"
+    "reflect"
+    "strconv"
+    "strings"
+
+    "github.com/francis/projectx-api/internal/secrets"
+)
+
+// applyEnv overrides every field that has an env tag with the value of that
+// variable, when the variable is set and not empty.
+func applyEnv(cfg *Config) error {
+    return walkEnv(reflect.ValueOf(cfg).Elem())
+}
+
+func walkEnv(v reflect.Value) error {
+    t := v.Type()
+    for i := 0; i < t.NumField(); i++ {
+        field := t.Field(i)
+        value := v.Field(i)
+
+        name := field.Tag.Get("env")
+        if name == "" {
+            if value.Kind() == reflect.Struct {
+                if err := walkEnv(value); err != nil {
+                    return err
+                }
+            }
+            continue
+        }
+
+        raw, err := lookupEnv(name)
+        if err != nil {
+            return err
+        }
+        if raw == "" {
+            continue
+        }
+        if err := setField(value, raw); err != nil {
+            return fmt.Errorf("%s: %w", name, err)
+        }
+    }
+    return nil
+}
+
+// lookupEnv returns the value of name, or the contents of the file named by
+// name_FILE as used with Docker and Kubernetes secrets. Setting both is an
+// error, since it is unclear which one was meant.
+func lookupEnv(name string) (string, error) {
+    value := os.Getenv(name)
+    path := os.Getenv(name + "_FILE")
+    switch {
+    case path == "":
+        return value, nil
+    case value != "":
+        return "", fmt.Errorf("both %s and %s_FILE are set", name, name)
+    }
+
+    value, err := secrets.ReadFile(path)
+    if err != nil {
+        return "", fmt.Errorf("%s_FILE: %w", name, err)
+    }
+    return value, nil
+}
+
+func setField(v reflect.Value, raw string) error {
+    if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+        return u.UnmarshalText([]byte(raw))
+    }
+
+    switch v.Kind() {
+    case reflect.String:
+        v.SetString(raw)
+    case reflect.Int:
+        n, err := strconv.Atoi(raw)
+        if err != nil {
+            return fmt.Errorf("invalid integer %q", raw)
+        }
+        v.SetInt(int64(n))
+    case reflect.Float64:
+        f, err := strconv.ParseFloat(raw, 64)
+        if err != nil {
+            return fmt.Errorf("invalid number %q", raw)
+        }
+        v.SetFloat(f)
+    case reflect.Bool:
+        b, err := strconv.ParseBool(raw)
+        if err != nil {
+            return fmt.Errorf("invalid boolean %q", raw)
+        }
+        v.SetBool(b)
+    case reflect.Slice:
+        // Lists are comma separated: ALLOWED_ORIGINS=https://a.com,https://b.com
+        var items []string
+        for _, item := range strings.Split(raw, ",") {
+            if item = strings.TrimSpace(item); item != "" {
+                items = append(items, item)
+            }
+        }
+        v.Set(reflect.ValueOf(items))
+    default:
+        return fmt.Errorf("unsupported config field type %s", v.Type())
+    }
+    return nil
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+    "io"
+    "reflect"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+)
+
+// WriteYAML writes the configuration in the same layout as a YAML config
+// file, so the output can be used as a starting point for one. Secret
+// fields are written as REDACTED.
+func (c *Config) WriteYAML(w io.Writer) error {
+    return c.writeYAML(w, false)
+}
+
+// WriteYAMLUnredacted is WriteYAML with the secret values written out. It
+// is meant for debugging a local setup; never expose its output.
+func (c *Config) WriteYAMLUnredacted(w io.Writer) error {
+    return c.writeYAML(w, true)
+}
+
+func (c *Config) writeYAML(w io.Writer, reveal bool) error {
+    var doc yaml.Node
+    if err := doc.Encode(c); err != nil {
+        return err
+    }
+    if reveal {
+        revealSecrets(&doc, reflect.ValueOf(c).Elem())
+    }
+
+    enc := yaml.NewEncoder(w)
+    enc.SetIndent(2)
+    if err := enc.Encode(&doc); err != nil {
+        return err
+    }
+    return enc.Close()
+}
+
+// revealSecrets replaces the redacted Secret fields of v in node, the
+// mapping v was encoded to, with their values.
+func revealSecrets(node *yaml.Node, v reflect.Value) {
+    if v.Kind() != reflect.Struct || node.Kind != yaml.MappingNode {
+        return
+    }
+
+    t := v.Type()
+    for i := 0; i < t.NumField(); i++ {
+        if !t.Field(i).IsExported() {
+            continue
+        }
+        name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+        if name == "" {
+            name = strings.ToLower(t.Field(i).Name)
+        }
+        value := mappingValue(node, name)
+        if value == nil {
+            continue
+        }
+
+        if secret, ok := v.Field(i).Interface().(Secret); ok {
+            value.Value = secret.Value()
+            continue
+        }
+        revealSecrets(value, v.Field(i))
+    }
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+    for i := 0; i+1 < len(node.Content); i += 2 {
+        if node.Content[i].Value == key {
+            return node.Content[i+1]
+        }
+    }
+    return nil
+}
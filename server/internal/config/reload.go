@@ -0,0 +1,97 @@
+package config
+
+import (
+    "reflect"
+    "sync"
+    "sync/atomic"
+)
+
+// ReloadResult describes what a reload changed. Sections listed in
+// RestartRequired differ in the new config but keep their running values
+// until the process restarts.
+type ReloadResult struct {
+    Changed         []string `json:"changed"`
+    RestartRequired []string `json:"restart_required"`
+}
+
+// Reloader holds the running configuration and re-reads it on demand. Only
+// the log section (but for its sinks), CORS, rate limit and feature sections
+// are swapped at runtime; everything else is fixed at startup.
+type Reloader struct {
+    path      string
+    mu        sync.Mutex
+    current   atomic.Pointer[Config]
+    listeners []func(*Config)
+}
+
+func NewReloader(path string, cfg *Config) *Reloader {
+    r := &Reloader{path: path}
+    r.current.Store(cfg)
+    return r
+}
+
+// Current returns the configuration in effect. Callers must not modify it.
+func (r *Reloader) Current() *Config {
+    return r.current.Load()
+}
+
+// OnReload registers fn to be called with the new configuration after every
+// successful reload that changed a reloadable section. Listeners must not
+// fail: by the time they run the new config has been accepted.
+func (r *Reloader) OnReload(fn func(*Config)) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    r.listeners = append(r.listeners, fn)
+}
+
+// Reload reads and validates the configuration again. If anything is wrong
+// the error is returned and the running configuration is left untouched.
+func (r *Reloader) Reload() (*ReloadResult, error) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    fresh, err := Read(r.path)
+    if err != nil {
+        return nil, err
+    }
+    if err := fresh.Validate(); err != nil {
+        return nil, err
+    }
+
+    old := r.current.Load()
+    next := *old
+    next.Log = fresh.Log
+    // Sinks are opened once at startup
+    next.Log.Sinks = old.Log.Sinks
+    next.CORS = fresh.CORS
+    next.RateLimit = fresh.RateLimit
+    next.Features = fresh.Features
+
+    result := &ReloadResult{
+        Changed:         changedSections(old, &next),
+        RestartRequired: changedSections(&next, fresh),
+    }
+    if len(result.Changed) == 0 {
+        return result, nil
+    }
+
+    r.current.Store(&next)
+    for _, fn := range r.listeners {
+        fn(&next)
+    }
+    return result, nil
+}
+
+// changedSections lists the top-level sections, by their config file name,
+// that differ between a and b.
+func changedSections(a, b *Config) []string {
+    changed := []string{}
+    va, vb := reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem()
+    t := va.Type()
+    for i := 0; i < t.NumField(); i++ {
+        if !reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+            changed = append(changed, t.Field(i).Tag.Get("yaml"))
+        }
+    }
+    return changed
+}
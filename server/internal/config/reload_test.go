@@ -0,0 +1,109 @@
+package config
+
+import (
+    "os"
+    "reflect"
+    "testing"
+)
+
+func TestReloaderReload(t *testing.T) {
+    tests := []struct {
+        name            string
+        body            string
+        wantErr         bool
+        wantChanged     []string
+        wantRestart     []string
+        wantLogLevel    string
+        wantHTTPPort    string
+        wantListenerRun bool
+    }{
+        {
+            name:         "nothing changed",
+            body:         "log:\n  level: info\n",
+            wantChanged:  []string{},
+            wantRestart:  []string{},
+            wantLogLevel: "info",
+            wantHTTPPort: "8080",
+        },
+        {
+            name:            "reloadable section",
+            body:            "log:\n  level: debug\nfeatures:\n  beta: true\n",
+            wantChanged:     []string{"log", "features"},
+            wantRestart:     []string{},
+            wantLogLevel:    "debug",
+            wantHTTPPort:    "8080",
+            wantListenerRun: true,
+        },
+        {
+            name:         "section fixed at startup",
+            body:         "log:\n  level: info\nhttp:\n  port: \"9191\"\n",
+            wantChanged:  []string{},
+            wantRestart:  []string{"http"},
+            wantLogLevel: "info",
+            wantHTTPPort: "8080",
+        },
+        {
+            name:         "log sinks are kept",
+            body:         "log:\n  level: info\n  sinks:\n    - type: stderr\n",
+            wantChanged:  []string{},
+            wantRestart:  []string{"log"},
+            wantLogLevel: "info",
+            wantHTTPPort: "8080",
+        },
+        {
+            name:         "invalid config is refused",
+            body:         "log:\n  level: loud\n",
+            wantErr:      true,
+            wantLogLevel: "info",
+            wantHTTPPort: "8080",
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            clearEnv(t)
+            path := writeFile(t, "config.yaml", "log:\n  level: info\n")
+            cfg, err := Load(path)
+            if err != nil {
+                t.Fatalf("Load: %v", err)
+            }
+            if cfg.HTTP.Port != "8080" {
+                t.Fatalf("default http.port = %q, the cases below expect 8080", cfg.HTTP.Port)
+            }
+
+            reloader := NewReloader(path, cfg)
+            listenerRun := false
+            reloader.OnReload(func(*Config) { listenerRun = true })
+
+            if err := os.WriteFile(path, []byte(tt.body), 0o600); err != nil {
+                t.Fatal(err)
+            }
+            result, err := reloader.Reload()
+            if (err != nil) != tt.wantErr {
+                t.Fatalf("Reload() error = %v, want error %v", err, tt.wantErr)
+            }
+            if err == nil {
+                if !reflect.DeepEqual(result.Changed, tt.wantChanged) {
+                    t.Errorf("changed = %q, want %q", result.Changed, tt.wantChanged)
+                }
+                if !reflect.DeepEqual(result.RestartRequired, tt.wantRestart) {
+                    t.Errorf("restart required = %q, want %q", result.RestartRequired, tt.wantRestart)
+                }
+            }
+
+            current := reloader.Current()
+            if current.Log.Level != tt.wantLogLevel {
+                t.Errorf("log.level = %q, want %q", current.Log.Level, tt.wantLogLevel)
+            }
+            if current.HTTP.Port != tt.wantHTTPPort {
+                t.Errorf("http.port = %q, want %q", current.HTTP.Port, tt.wantHTTPPort)
+            }
+            if !reflect.DeepEqual(current.Log.Sinks, cfg.Log.Sinks) {
+                t.Errorf("log.sinks = %+v, want the startup sinks %+v", current.Log.Sinks, cfg.Log.Sinks)
+            }
+            if listenerRun != tt.wantListenerRun {
+                t.Errorf("listener run = %v, want %v", listenerRun, tt.wantListenerRun)
+            }
+        })
+    }
+}
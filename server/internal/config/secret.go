@@ -0,0 +1,57 @@
+package config
+
+import (
+    "log/slog"
+    "net/url"
+)
+
+const redacted = "REDACTED"
+
+// Secret is a config value that must not be shown. It prints, logs and
+// marshals as REDACTED (for URLs only the password is masked, so the host
+// stays visible); call Value to use it.
+type Secret string
+
+func (s Secret) Value() string {
+    return string(s)
+}
+
+func (s Secret) String() string {
+    return s.redacted()
+}
+
+func (s Secret) GoString() string {
+    return `"` + s.redacted() + `"`
+}
+
+func (s Secret) LogValue() slog.Value {
+    return slog.StringValue(s.redacted())
+}
+
+func (s Secret) MarshalText() ([]byte, error) {
+    return []byte(s.redacted()), nil
+}
+
+func (s *Secret) UnmarshalText(text []byte) error {
+    *s = Secret(text)
+    return nil
+}
+
+func (s Secret) redacted() string {
+    if s == "" {
+        return ""
+    }
+
+    u, err := url.Parse(string(s))
+    if err != nil || u.Scheme == "" || u.Host == "" || u.Query().Has("password") {
+        return redacted
+    }
+    if u.User != nil {
+        // A user without a password may itself be a token.
+        if _, ok := u.User.Password(); !ok {
+            return redacted
+        }
+        u.User = url.UserPassword(u.User.Username(), redacted)
+    }
+    return u.String()
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+    "context"
+    "fmt"
+    "reflect"
+    "strings"
+
+    "github.com/francis/projectx-api/internal/secrets"
+)
+
+// secretRefPrefix marks a Secret value as the name of a secret held by the
+// secrets provider rather than the secret itself.
+const secretRefPrefix = "secret://"
+
+var secretType = reflect.TypeOf(Secret(""))
+
+// resolveSecrets replaces secret:// references in Secret fields with the
+// values from the configured provider. The provider is only created when a
+// reference is found.
+func resolveSecrets(cfg *Config) error {
+    var provider secrets.Provider
+    return walkSecrets(reflect.ValueOf(cfg).Elem(), func(field reflect.Value) error {
+        name, ok := strings.CutPrefix(field.String(), secretRefPrefix)
+        if !ok {
+            return nil
+        }
+
+        if provider == nil {
+            var err error
+            if provider, err = newSecretsProvider(cfg.Secrets); err != nil {
+                return err
+            }
+        }
+        value, err := provider.Get(context.Background(), name)
+        if err != nil {
+            return fmt.Errorf("resolve %s%s: %w", secretRefPrefix, name, err)
+        }
+        field.SetString(value)
+        return nil
+    })
+}
+
+func walkSecrets(v reflect.Value, fn func(reflect.Value) error) error {
+    for i := 0; i < v.NumField(); i++ {
+        field := v.Field(i)
+        switch {
+        case field.Type() == secretType:
+            if err := fn(field); err != nil {
+                return err
+            }
+        case field.Kind() == reflect.Struct:
+            if err := walkSecrets(field, fn); err != nil {
+                return err
+            }
+        }
+    }
+    return nil
+}
+
+func newSecretsProvider(cfg SecretsConfig) (secrets.Provider, error) {
+    switch cfg.Provider {
+    case "file":
+        return secrets.NewFileProvider(cfg.Dir), nil
+    case "age":
+        return secrets.NewAgeProvider(cfg.AgeFile, cfg.AgeIdentityFile)
+    case "":
+        return nil, fmt.Errorf("secrets.provider (SECRETS_PROVIDER) must be set to use %s references", secretRefPrefix)
+    default:
+        return nil, fmt.Errorf("unknown secrets provider %q", cfg.Provider)
+    }
+}
@@ -0,0 +1,159 @@
+package config
+
+import (
+    "errors"
+    "fmt"
+    "net"
+    "strconv"
+    "strings"
+)
+
+// minJWTSecretLength is the shortest JWT secret accepted in production;
+// HS256 keys should be at least as long as the hash output.
+const minJWTSecretLength = 32
+
+// minDebugTokenLength is the shortest debug listener token accepted.
+const minDebugTokenLength = 32
+
+var logLevels = map[string]bool{"debug": true, "info": true, "warn": true, "error": true}
+
+var schemaChecks = map[string]bool{SchemaCheckFail: true, SchemaCheckDegraded: true, SchemaCheckOff: true}
+
+var redactions = map[string]bool{RedactEmail: true, RedactToken: true, RedactAuthorization: true}
+
+var logSinkTypes = map[string]bool{LogSinkStdout: true, LogSinkStderr: true, LogSinkFile: true, LogSinkSyslog: true}
+
+var logFormats = map[string]bool{"": true, LogFormatJSON: true, LogFormatConsole: true}
+
+var traceExporters = map[string]bool{"none": true, "stdout": true, "otlp": true}
+
+var rateLimitBackends = map[string]bool{RateLimitBackendMemory: true, RateLimitBackendRedis: true}
+
+var rateLimitAlgorithms = map[string]bool{RateLimitGCRA: true, RateLimitSlidingWindow: true}
+
+var syslogNetworks = map[string]bool{"": true, "udp": true, "tcp": true, "unix": true, "unixgram": true}
+
+// Validate reports every problem with the configuration at once. In
+// production it also refuses the built-in development defaults, so a
+// missing variable stops the server instead of running it insecurely.
+func (c *Config) Validate() error {
+    var errs []error
+    check := func(ok bool, format string, args ...interface{}) {
+        if !ok {
+            errs = append(errs, fmt.Errorf(format, args...))
+        }
+    }
+
+    check(c.Environment != "", "environment must be set")
+
+    port, err := strconv.Atoi(c.HTTP.Port)
+    check(err == nil && port > 0 && port < 65536, "http.port must be a TCP port, got %q", c.HTTP.Port)
+    check(c.HTTP.ReadTimeout.Duration > 0, "http.read_timeout must be positive")
+    check(c.HTTP.WriteTimeout.Duration > 0, "http.write_timeout must be positive")
+    check(c.HTTP.IdleTimeout.Duration > 0, "http.idle_timeout must be positive")
+    check(c.HTTP.ShutdownTimeout.Duration > 0, "http.shutdown_timeout must be positive")
+    check(c.HTTP.ShutdownDelay.Duration >= 0, "http.shutdown_delay must not be negative")
+
+    if c.Metrics.Enabled && c.Metrics.Port != "" {
+        port, err := strconv.Atoi(c.Metrics.Port)
+        check(err == nil && port > 0 && port < 65536, "metrics.port must be a TCP port, got %q", c.Metrics.Port)
+        check(c.Metrics.Port != c.HTTP.Port, "metrics.port must differ from http.port; leave it empty to serve metrics on the API port")
+    }
+
+    check(c.DB.URL != "", "db.url must be set")
+    check(c.DB.MaxOpenConns >= 0, "db.max_open_conns must not be negative")
+    check(c.DB.MaxIdleConns >= 0, "db.max_idle_conns must not be negative")
+    check(c.DB.MaxOpenConns == 0 || c.DB.MaxIdleConns <= c.DB.MaxOpenConns, "db.max_idle_conns must not exceed db.max_open_conns")
+    check(schemaChecks[c.DB.SchemaCheck], "db.schema_check must be one of fail, degraded, off")
+    check(c.DB.SlowQueryThreshold.Duration >= 0, "db.slow_query_threshold must not be negative")
+    check(c.DB.NPlusOneThreshold >= 0, "db.n_plus_one_threshold must not be negative")
+
+    check(c.Auth.JWTSecret != "", "auth.jwt_secret must be set")
+    check(c.Auth.AccessTokenTTL.Duration > 0, "auth.access_token_ttl must be positive")
+    check(c.Auth.RefreshTokenTTL.Duration >= c.Auth.AccessTokenTTL.Duration, "auth.refresh_token_ttl must not be shorter than auth.access_token_ttl")
+
+    if c.RateLimit.Enabled {
+        check(c.RateLimit.Requests > 0, "ratelimit.requests must be positive")
+        check(c.RateLimit.Window.Duration > 0, "ratelimit.window must be positive")
+        check(rateLimitBackends[c.RateLimit.Backend], "ratelimit.backend must be memory or redis")
+        check(rateLimitAlgorithms[c.RateLimit.Algorithm], "ratelimit.algorithm must be gcra or sliding_window")
+        if c.RateLimit.Backend == RateLimitBackendRedis {
+            check(c.Redis.URL != "", "redis.url must be set for the redis rate limit backend")
+            check(c.Redis.Timeout.Duration > 0, "redis.timeout must be positive")
+        }
+    }
+
+    check(len(c.CORS.AllowedOrigins) > 0, "cors.allowed_origins must not be empty")
+    for _, origin := range c.CORS.AllowedOrigins {
+        check(origin == "*" || strings.HasPrefix(origin, "http://") || strings.HasPrefix(origin, "https://"),
+            "cors.allowed_origins entry %q must start with http:// or https://", origin)
+    }
+    if c.CORS.AllowCredentials {
+        check(!allowsAnyOrigin(c.CORS.AllowedOrigins), "cors.allow_credentials cannot be used with the \"*\" origin")
+    }
+
+    if c.Mail.SMTPHost != "" {
+        check(c.Mail.SMTPPort > 0, "mail.smtp_port must be positive")
+        check(c.Mail.From != "", "mail.from must be set when mail.smtp_host is")
+    }
+
+    check(traceExporters[c.Tracing.Exporter], "tracing.exporter must be one of none, stdout, otlp")
+    check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio must be between 0 and 1")
+    check(c.Tracing.ServiceName != "", "tracing.service_name must be set")
+
+    if c.Debug.Enabled {
+        host, port, err := net.SplitHostPort(c.Debug.Addr)
+        check(err == nil && port != "", "debug.addr must be host:port, got %q", c.Debug.Addr)
+        check(err != nil || (port != c.HTTP.Port && port != c.Metrics.Port), "debug.addr must not use the http or metrics port")
+        check(err != nil || host != "", "debug.addr must name a host; bind it to localhost or an internal interface")
+        check(len(c.Debug.Token) >= minDebugTokenLength, "debug.token must be at least %d characters", minDebugTokenLength)
+    }
+
+    check(logLevels[c.Log.Level], "log.level must be one of debug, info, warn, error")
+    check(len(c.Log.Sinks) > 0, "log.sinks must not be empty")
+    for i, sink := range c.Log.Sinks {
+        check(logSinkTypes[sink.Type], "log.sinks[%d].type must be one of stdout, stderr, file, syslog", i)
+        check(logFormats[sink.Format], "log.sinks[%d].format must be json or console", i)
+        check(sink.Level == "" || logLevels[sink.Level], "log.sinks[%d].level must be one of debug, info, warn, error", i)
+        switch sink.Type {
+        case LogSinkFile:
+            check(sink.Path != "", "log.sinks[%d].path must be set for a file sink", i)
+            check(sink.MaxSizeMB >= 0 && sink.MaxBackups >= 0 && sink.MaxAge.Duration >= 0, "log.sinks[%d] rotation limits must not be negative", i)
+        case LogSinkSyslog:
+            check(syslogNetworks[sink.Network], "log.sinks[%d].network must be one of udp, tcp, unix, unixgram", i)
+            check(sink.Network == "" || sink.Address != "", "log.sinks[%d].address must be set with a network", i)
+        }
+    }
+    if c.Log.Sampling.First > 0 {
+        check(c.Log.Sampling.Thereafter >= 0, "log.sampling.thereafter must not be negative")
+        check(c.Log.Sampling.Period.Duration > 0, "log.sampling.period must be positive")
+    }
+    for _, kind := range c.Log.Access.Redact {
+        check(redactions[kind], "log.access.redact entry %q must be one of email, token, authorization", kind)
+    }
+
+    if c.IsProduction() {
+        if c.Auth.JWTSecret == defaultJWTSecret {
+            errs = append(errs, errors.New("auth.jwt_secret (JWT_SECRET) must be set in production"))
+        } else {
+            check(len(c.Auth.JWTSecret) >= minJWTSecretLength, "auth.jwt_secret must be at least %d characters in production", minJWTSecretLength)
+        }
+        check(c.DB.URL != defaultDatabaseURL, "db.url (DATABASE_URL) must be set in production")
+        check(!c.DB.ExplainSlowQueries, "db.explain_slow_queries must be off in production")
+        check(!allowsAnyOrigin(c.CORS.AllowedOrigins), "cors.allowed_origins (ALLOWED_ORIGINS) must list explicit origins in production")
+    }
+
+    if len(errs) > 0 {
+        return fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+    }
+    return nil
+}
+
+func allowsAnyOrigin(origins []string) bool {
+    for _, origin := range origins {
+        if origin == "*" {
+            return true
+        }
+    }
+    return false
+}
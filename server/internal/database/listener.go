@@ -0,0 +1,45 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Listen calls fn with the payload of every NOTIFY sent on channel until ctx
+// is done. Notifications sent while the connection is down are lost, so
+// after reconnecting fn is called with an empty payload to signal that the
+// caller should reload everything.
+func Listen(ctx context.Context, databaseURL, channel string, fn func(payload string)) error {
+    listener := pq.NewListener(databaseURL, 10*time.Second, time.Minute, nil)
+    if err := listener.Listen(channel); err != nil {
+        listener.Close()
+        return fmt.Errorf("failed to listen on %s: %w", channel, err)
+    }
+
+    go func() {
+        defer listener.Close()
+
+        // Ping now and then so a dead connection is noticed and replaced
+        ticker := time.NewTicker(90 * time.Second)
+        defer ticker.Stop()
+
+        for {
+            select {
+            case <-ctx.Done():
+                return
+            case n := <-listener.Notify:
+                if n == nil {
+                    fn("")
+                    continue
+                }
+                fn(n.Extra)
+            case <-ticker.C:
+                go listener.Ping()
+            }
+        }
+    }()
+    return nil
+}
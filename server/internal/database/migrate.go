@@ -0,0 +1,174 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/francis/projectx-api/migrations"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/lib/pq"
+)
+
+// migrationLockID is the pg_advisory_lock key held while migrating, so
+// replicas started together with --migrate apply migrations one at a time.
+const migrationLockID = 7_316_504_211
+
+// MigrationStatus describes the schema of a database relative to the
+// migrations embedded in the binary.
+type MigrationStatus struct {
+    Version uint `json:"version"`
+    Dirty   bool `json:"dirty"`
+    Latest  uint `json:"latest"`
+    Pending int  `json:"pending"`
+}
+
+// withMigrate runs fn with a migrate instance on a single connection taken
+// from db. Handing migrate a connection rather than db stops it closing db,
+// and lets the advisory lock cover everything fn does.
+func withMigrate(ctx context.Context, db *sql.DB, fn func(*migrate.Migrate) error) error {
+    conn, err := db.Conn(ctx)
+    if err != nil {
+        return fmt.Errorf("failed to get connection: %w", err)
+    }
+    // Closing conn also releases the migrate driver built on it; the
+    // migrate instance itself holds nothing else that needs closing.
+    defer conn.Close()
+
+    if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+        return fmt.Errorf("failed to take migration lock: %w", err)
+    }
+    defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+
+    driver, err := postgres.WithConnection(ctx, conn, &postgres.Config{})
+    if err != nil {
+        return fmt.Errorf("failed to create migrate driver: %w", err)
+    }
+
+    source, err := iofs.New(migrations.FS, ".")
+    if err != nil {
+        return fmt.Errorf("failed to read embedded migrations: %w", err)
+    }
+
+    m, err := migrate.NewWithInstance("iofs", source, "postgres", driver)
+    if err != nil {
+        return fmt.Errorf("failed to create migrate instance: %w", err)
+    }
+    return fn(m)
+}
+
+// RunMigrations applies every pending migration.
+func RunMigrations(ctx context.Context, db *sql.DB) error {
+    return withMigrate(ctx, db, func(m *migrate.Migrate) error {
+        if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+            return fmt.Errorf("failed to run migrations: %w", err)
+        }
+        return nil
+    })
+}
+
+// MigrateDown rolls back the given number of migrations.
+func MigrateDown(ctx context.Context, db *sql.DB, steps int) error {
+    return withMigrate(ctx, db, func(m *migrate.Migrate) error {
+        if err := m.Steps(-steps); err != nil && err != migrate.ErrNoChange {
+            return fmt.Errorf("failed to rollback migrations: %w", err)
+        }
+        return nil
+    })
+}
+
+// ForceMigrationVersion records version as applied and clears the dirty
+// flag without running anything, to recover from a failed migration once
+// the database has been repaired by hand.
+func ForceMigrationVersion(ctx context.Context, db *sql.DB, version int) error {
+    return withMigrate(ctx, db, func(m *migrate.Migrate) error {
+        if err := m.Force(version); err != nil {
+            return fmt.Errorf("failed to force version %d: %w", version, err)
+        }
+        return nil
+    })
+}
+
+// GetMigrationStatus reports the applied version of db. It reads the
+// golang-migrate version table directly instead of taking the migration
+// lock, so it answers straight away while another instance is migrating
+// and is cheap enough to call from health checks.
+func GetMigrationStatus(ctx context.Context, db *sql.DB) (*MigrationStatus, error) {
+    versions, err := EmbeddedMigrations()
+    if err != nil {
+        return nil, err
+    }
+
+    status := &MigrationStatus{}
+    if len(versions) > 0 {
+        status.Latest = versions[len(versions)-1]
+    }
+
+    var version int64
+    err = db.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &status.Dirty)
+    var pqErr *pq.Error
+    switch {
+    case errors.Is(err, sql.ErrNoRows):
+        // Every migration was rolled back.
+    case errors.As(err, &pqErr) && pqErr.Code == "42P01":
+        // undefined_table: nothing has been migrated yet.
+    case err != nil:
+        return nil, fmt.Errorf("failed to read migration version: %w", err)
+    default:
+        status.Version = uint(version)
+    }
+
+    for _, v := range versions {
+        if v > status.Version {
+            status.Pending++
+        }
+    }
+    return status, nil
+}
+
+// Current reports whether the schema is exactly what the embedded
+// migrations produce.
+func (s *MigrationStatus) Current() bool {
+    return !s.Dirty && s.Version == s.Latest
+}
+
+// Problem describes why the schema is not current, or returns "" if it is.
+func (s *MigrationStatus) Problem() string {
+    switch {
+    case s.Dirty:
+        return fmt.Sprintf("migration %d failed and left the schema dirty; repair it and run 'api migrate force %d'", s.Version, s.Version)
+    case s.Version < s.Latest:
+        return fmt.Sprintf("schema is at version %d but this build expects %d; run 'api migrate up' or start with --migrate", s.Version, s.Latest)
+    case s.Version > s.Latest:
+        return fmt.Sprintf("schema is at version %d, newer than the %d this build knows; deploy a newer build or roll the schema back", s.Version, s.Latest)
+    }
+    return ""
+}
+
+// EmbeddedMigrations returns the versions of the embedded migrations in
+// ascending order.
+func EmbeddedMigrations() ([]uint, error) {
+    names, err := fs.Glob(migrations.FS, "*.up.sql")
+    if err != nil {
+        return nil, err
+    }
+
+    versions := make([]uint, 0, len(names))
+    for _, name := range names {
+        prefix, _, _ := strings.Cut(name, "_")
+        v, err := strconv.ParseUint(prefix, 10, 64)
+        if err != nil {
+            return nil, fmt.Errorf("migration %s has no numeric version", name)
+        }
+        versions = append(versions, uint(v))
+    }
+    sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+    return versions, nil
+}
@@ -3,13 +3,14 @@ package database
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
 	"time"
 
-	"github.com/golang-migrate/migrate/v4"
-	"github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
-	_ "github.com/lib/pq"
+	"github.com/XSAM/otelsql"
+	"github.com/francis/projectx-api/internal/tracing"
+	"github.com/lib/pq"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 )
 
 type DB struct {
@@ -26,20 +27,53 @@ type Config struct {
     SSLMode  string
 }
 
+// PoolConfig holds connection pool limits
+type PoolConfig struct {
+    MaxOpenConns    int
+    MaxIdleConns    int
+    ConnMaxLifetime time.Duration
+    ConnMaxIdleTime time.Duration
+}
+
+// DefaultPoolConfig is used when no pool limits are configured
+var DefaultPoolConfig = PoolConfig{
+    MaxOpenConns:    25,
+    MaxIdleConns:    5,
+    ConnMaxLifetime: 5 * time.Minute,
+}
+
 // NewPostgresDB creates a new PostgreSQL database connection
-func NewPostgresDB(databaseURL string) (*sql.DB, error) {
-    db, err := sql.Open("postgres", databaseURL)
+//
+// Queries made while a trace is in progress get a span of their own, and
+// statements are timed and counted as set by queries.
+func NewPostgresDB(databaseURL string, pool PoolConfig, queries QueryLogConfig) (*sql.DB, error) {
+    connector, err := pq.NewConnector(databaseURL)
     if err != nil {
         return nil, fmt.Errorf("failed to open database: %w", err)
     }
+    ql := &queryLog{cfg: queries}
+    db := otelsql.OpenDB(&loggingConnector{inner: connector, log: ql},
+        otelsql.WithAttributes(semconv.DBSystemPostgreSQL),
+        otelsql.WithSpanOptions(otelsql.SpanOptions{
+            OmitConnResetSession: true,
+            OmitRows:             true,
+            DisableErrSkip:       true,
+            SpanFilter: func(ctx context.Context, _ otelsql.Method, _ string, _ []driver.NamedValue) bool {
+                return tracing.Traced(ctx)
+            },
+        }),
+    )
+    ql.db = db
 
     // Configure connection pool
-    db.SetMaxOpenConns(25)
-    db.SetMaxIdleConns(5)
-    db.SetConnMaxLifetime(5 * time.Minute)
+    db.SetMaxOpenConns(pool.MaxOpenConns)
+    db.SetMaxIdleConns(pool.MaxIdleConns)
+    db.SetConnMaxLifetime(pool.ConnMaxLifetime)
+    db.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
 
     // Test the connection
     if err := db.Ping(); err != nil {
+        db.Close()
         return nil, fmt.Errorf("failed to ping database: %w", err)
     }
 
@@ -51,55 +85,7 @@ func NewPostgresDBWithConfig(cfg Config) (*sql.DB, error) {
     dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
         cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName, cfg.SSLMode)
     
-    return NewPostgresDB(dsn)
-}
-
-// RunMigrations runs database migrations
-func RunMigrations(db *sql.DB, migrationsPath string) error {
-    driver, err := postgres.WithInstance(db, &postgres.Config{})
-    if err != nil {
-        return fmt.Errorf("failed to create migrate driver: %w", err)
-    }
-
-    m, err := migrate.NewWithDatabaseInstance(
-        fmt.Sprintf("file://%s", migrationsPath),
-        "postgres", 
-        driver,
-    )
-    if err != nil {
-        return fmt.Errorf("failed to create migrate instance: %w", err)
-    }
-    defer m.Close()
-
-    if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-        return fmt.Errorf("failed to run migrations: %w", err)
-    }
-
-    return nil
-}
-
-// MigrateDown rolls back database migrations
-func MigrateDown(db *sql.DB, migrationsPath string) error {
-    driver, err := postgres.WithInstance(db, &postgres.Config{})
-    if err != nil {
-        return fmt.Errorf("failed to create migrate driver: %w", err)
-    }
-
-    m, err := migrate.NewWithDatabaseInstance(
-        fmt.Sprintf("file://%s", migrationsPath),
-        "postgres", 
-        driver,
-    )
-    if err != nil {
-        return fmt.Errorf("failed to create migrate instance: %w", err)
-    }
-    defer m.Close()
-
-    if err := m.Down(); err != nil && err != migrate.ErrNoChange {
-        return fmt.Errorf("failed to rollback migrations: %w", err)
-    }
-
-    return nil
+    return NewPostgresDB(dsn, DefaultPoolConfig, QueryLogConfig{})
 }
 
 // HealthCheck checks if the database is healthy
@@ -0,0 +1,315 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/francis/projectx-api/pkg/logger"
+)
+
+// QueryLogConfig controls what the driver wrapper logs. Statements taking
+// SlowThreshold or longer are logged with their normalized SQL and the
+// code that ran them; zero turns this off. With Explain set the plan of
+// each slow statement is logged too, which costs a query of its own and is
+// meant for development.
+type QueryLogConfig struct {
+    SlowThreshold time.Duration
+    Explain       bool
+}
+
+// explainTimeout bounds the EXPLAIN run for a slow statement.
+const explainTimeout = 5 * time.Second
+
+// queryLog times every statement run through the wrapped driver and counts
+// it against the request's QueryStats.
+type queryLog struct {
+    cfg QueryLogConfig
+    // Set once the pool is open; EXPLAIN runs on a connection of its own
+    db *sql.DB
+}
+
+type explainKey struct{}
+
+func (q *queryLog) observe(ctx context.Context, query string, args []driver.NamedValue, start time.Time) {
+    if ctx.Value(explainKey{}) != nil {
+        return
+    }
+    elapsed := time.Since(start)
+    if stats := queryStatsFromContext(ctx); stats != nil {
+        stats.add(query)
+    }
+    if q.cfg.SlowThreshold <= 0 || elapsed < q.cfg.SlowThreshold {
+        return
+    }
+
+    // Arguments are not logged: they hold emails and password hashes
+    log := logger.FromContext(ctx)
+    normalized := NormalizeQuery(query)
+    log.Warn("Slow query",
+        "query", normalized,
+        "duration_ms", float64(elapsed.Microseconds())/1000,
+        "threshold_ms", q.cfg.SlowThreshold.Milliseconds(),
+        "caller", caller(),
+    )
+    if q.cfg.Explain && q.db != nil && explainable(query) {
+        go q.explain(log, query, normalized, args)
+    }
+}
+
+func (q *queryLog) explain(log logger.Logger, query, normalized string, args []driver.NamedValue) {
+    ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), explainKey{}, true), explainTimeout)
+    defer cancel()
+
+    values := make([]interface{}, len(args))
+    for i, arg := range args {
+        values[i] = arg.Value
+    }
+    rows, err := q.db.QueryContext(ctx, "EXPLAIN "+query, values...)
+    if err != nil {
+        log.Warn("Failed to explain slow query", "query", normalized, "error", err.Error())
+        return
+    }
+    defer rows.Close()
+
+    var plan []string
+    for rows.Next() {
+        var line string
+        if err := rows.Scan(&line); err != nil {
+            log.Warn("Failed to explain slow query", "query", normalized, "error", err.Error())
+            return
+        }
+        plan = append(plan, line)
+    }
+    log.Info("Slow query plan", "query", normalized, "plan", strings.Join(plan, "\n"))
+}
+
+// explainable reports whether EXPLAIN accepts the statement.
+func explainable(query string) bool {
+    fields := strings.Fields(query)
+    if len(fields) == 0 {
+        return false
+    }
+    switch strings.ToUpper(fields[0]) {
+    case "SELECT", "WITH", "INSERT", "UPDATE", "DELETE", "VALUES":
+        return true
+    }
+    return false
+}
+
+var (
+    sqlString    = regexp.MustCompile(`'(?:[^']|'')*'`)
+    sqlNumber    = regexp.MustCompile(`([^\w$.])-?\d+(?:\.\d+)?\b`)
+    sqlValueList = regexp.MustCompile(`\(\s*(?:\?|\$\d+)(?:\s*,\s*(?:\?|\$\d+))+\s*\)`)
+    sqlSpace     = regexp.MustCompile(`\s+`)
+)
+
+// NormalizeQuery replaces the literals in query with ? and collapses value
+// lists and whitespace, so statements that differ only in their values
+// read the same.
+func NormalizeQuery(query string) string {
+    query = sqlString.ReplaceAllString(query, "?")
+    query = sqlNumber.ReplaceAllString(query, "${1}?")
+    query = sqlValueList.ReplaceAllString(query, "(...)")
+    return strings.TrimSpace(sqlSpace.ReplaceAllString(query, " "))
+}
+
+// caller returns the first frame outside database/sql and the driver
+// wrappers, which is the repository method that ran the statement.
+func caller() string {
+    pcs := make([]uintptr, 32)
+    n := runtime.Callers(3, pcs)
+    frames := runtime.CallersFrames(pcs[:n])
+    for {
+        frame, more := frames.Next()
+        if !internalFrame(frame.Function) {
+            function := frame.Function[strings.LastIndex(frame.Function, "/")+1:]
+            return fmt.Sprintf("%s (%s:%d)", function, filepath.Base(frame.File), frame.Line)
+        }
+        if !more {
+            return "unknown"
+        }
+    }
+}
+
+func internalFrame(function string) bool {
+    for _, prefix := range []string{
+        "database/sql.",
+        "github.com/francis/projectx-api/internal/database.",
+        "github.com/XSAM/otelsql.",
+        "runtime.",
+    } {
+        if strings.HasPrefix(function, prefix) {
+            return true
+        }
+    }
+    return false
+}
+
+// QueryStats counts the statements run with one context, normally one
+// request, so repeated statements can be reported.
+type QueryStats struct {
+    mu      sync.Mutex
+    total   int
+    byQuery map[string]int
+}
+
+// RepeatedQuery is a normalized statement and how often it ran.
+type RepeatedQuery struct {
+    Query string
+    Count int
+}
+
+// maxTrackedQueries bounds the distinct statements counted per context.
+const maxTrackedQueries = 256
+
+type queryStatsKey struct{}
+
+// WithQueryStats returns a context whose statements are counted in the
+// returned QueryStats.
+func WithQueryStats(ctx context.Context) (context.Context, *QueryStats) {
+    stats := &QueryStats{byQuery: make(map[string]int)}
+    return context.WithValue(ctx, queryStatsKey{}, stats), stats
+}
+
+func queryStatsFromContext(ctx context.Context) *QueryStats {
+    stats, _ := ctx.Value(queryStatsKey{}).(*QueryStats)
+    return stats
+}
+
+func (s *QueryStats) add(query string) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.total++
+    key := NormalizeQuery(query)
+    if _, ok := s.byQuery[key]; ok || len(s.byQuery) < maxTrackedQueries {
+        s.byQuery[key]++
+    }
+}
+
+// Total returns the number of statements run.
+func (s *QueryStats) Total() int {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return s.total
+}
+
+// Repeated returns the statements run at least n times, most frequent
+// first.
+func (s *QueryStats) Repeated(n int) []RepeatedQuery {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    var repeated []RepeatedQuery
+    for query, count := range s.byQuery {
+        if count >= n {
+            repeated = append(repeated, RepeatedQuery{Query: query, Count: count})
+        }
+    }
+    sort.Slice(repeated, func(i, j int) bool { return repeated[i].Count > repeated[j].Count })
+    return repeated
+}
+
+// fullConn is what the wrapper needs from a driver connection; lib/pq
+// connections provide all of it.
+type fullConn interface {
+    driver.Conn
+    driver.QueryerContext
+    driver.ExecerContext
+    driver.ConnPrepareContext
+    driver.ConnBeginTx
+    driver.Pinger
+    driver.SessionResetter
+    driver.Validator
+}
+
+type fullStmt interface {
+    driver.Stmt
+    driver.StmtExecContext
+    driver.StmtQueryContext
+}
+
+// loggingConnector wraps the connections of another connector so their
+// statements are timed and counted.
+type loggingConnector struct {
+    inner driver.Connector
+    log   *queryLog
+}
+
+func (c *loggingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+    conn, err := c.inner.Connect(ctx)
+    if err != nil {
+        return nil, err
+    }
+    full, ok := conn.(fullConn)
+    if !ok {
+        return conn, nil
+    }
+    return &loggingConn{fullConn: full, log: c.log}, nil
+}
+
+func (c *loggingConnector) Driver() driver.Driver {
+    return c.inner.Driver()
+}
+
+type loggingConn struct {
+    fullConn
+    log *queryLog
+}
+
+func (c *loggingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+    start := time.Now()
+    rows, err := c.fullConn.QueryContext(ctx, query, args)
+    if err != driver.ErrSkip {
+        c.log.observe(ctx, query, args, start)
+    }
+    return rows, err
+}
+
+func (c *loggingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+    start := time.Now()
+    result, err := c.fullConn.ExecContext(ctx, query, args)
+    if err != driver.ErrSkip {
+        c.log.observe(ctx, query, args, start)
+    }
+    return result, err
+}
+
+func (c *loggingConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+    stmt, err := c.fullConn.PrepareContext(ctx, query)
+    if err != nil {
+        return nil, err
+    }
+    full, ok := stmt.(fullStmt)
+    if !ok {
+        return stmt, nil
+    }
+    return &loggingStmt{fullStmt: full, query: query, log: c.log}, nil
+}
+
+type loggingStmt struct {
+    fullStmt
+    query string
+    log   *queryLog
+}
+
+func (s *loggingStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+    start := time.Now()
+    rows, err := s.fullStmt.QueryContext(ctx, args)
+    s.log.observe(ctx, s.query, args, start)
+    return rows, err
+}
+
+func (s *loggingStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+    start := time.Now()
+    result, err := s.fullStmt.ExecContext(ctx, args)
+    s.log.observe(ctx, s.query, args, start)
+    return result, err
+}
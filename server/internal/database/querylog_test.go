@@ -0,0 +1,147 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNormalizeQuery(t *testing.T) {
+    tests := []struct {
+        name  string
+        query string
+        want  string
+    }{
+        {"number", "SELECT * FROM users WHERE id = 42", "SELECT * FROM users WHERE id = ?"},
+        {"negative and decimal", "UPDATE t SET a = -5, b = 3.14", "UPDATE t SET a = ?, b = ?"},
+        {"string", "SELECT id FROM users WHERE email = 'jane@example.com'", "SELECT id FROM users WHERE email = ?"},
+        {"escaped quote", "SELECT id FROM users WHERE last_name = 'O''Brien' AND id > 1", "SELECT id FROM users WHERE last_name = ? AND id > ?"},
+        {"placeholders kept", "SELECT * FROM users WHERE id = $1", "SELECT * FROM users WHERE id = $1"},
+        {"identifiers kept", "SELECT col1, t2.x FROM table1 t2", "SELECT col1, t2.x FROM table1 t2"},
+        {"placeholder list", "SELECT * FROM users WHERE id IN ($1, $2, $3)", "SELECT * FROM users WHERE id IN (...)"},
+        {"literal lists", "INSERT INTO t (a, b) VALUES (1, 'x'), (2, 'y')", "INSERT INTO t (a, b) VALUES (...), (...)"},
+        {"single value kept", "SELECT * FROM users WHERE id IN ($1)", "SELECT * FROM users WHERE id IN ($1)"},
+        {"whitespace", "\n    SELECT id\n    FROM users\n    LIMIT 10 OFFSET 20\n", "SELECT id FROM users LIMIT ? OFFSET ?"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := NormalizeQuery(tt.query); got != tt.want {
+                t.Errorf("NormalizeQuery(%q) = %q, want %q", tt.query, got, tt.want)
+            }
+        })
+    }
+}
+
+func TestQueryStats(t *testing.T) {
+    tests := []struct {
+        name      string
+        queries   []string
+        n         int
+        wantTotal int
+        want      []RepeatedQuery
+    }{
+        {
+            name:      "nothing run",
+            n:         2,
+            wantTotal: 0,
+        },
+        {
+            name:      "below threshold",
+            queries:   []string{"SELECT 1", "SELECT * FROM roles WHERE user_id = $1"},
+            n:         2,
+            wantTotal: 2,
+        },
+        {
+            name: "values differ, statement repeats",
+            queries: []string{
+                "SELECT * FROM users",
+                "SELECT * FROM roles WHERE user_id = 1",
+                "SELECT * FROM roles WHERE user_id = 2",
+                "SELECT * FROM roles WHERE user_id = 3",
+                "SELECT * FROM attrs WHERE user_id = $1",
+                "SELECT * FROM attrs WHERE user_id = $1",
+            },
+            n:         2,
+            wantTotal: 6,
+            want: []RepeatedQuery{
+                {Query: "SELECT * FROM roles WHERE user_id = ?", Count: 3},
+                {Query: "SELECT * FROM attrs WHERE user_id = $1", Count: 2},
+            },
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            ctx, stats := WithQueryStats(context.Background())
+            ql := &queryLog{}
+            for _, query := range tt.queries {
+                ql.observe(ctx, query, nil, time.Now())
+            }
+
+            if got := stats.Total(); got != tt.wantTotal {
+                t.Errorf("Total() = %d, want %d", got, tt.wantTotal)
+            }
+            if got := stats.Repeated(tt.n); !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("Repeated(%d) = %+v, want %+v", tt.n, got, tt.want)
+            }
+        })
+    }
+}
+
+func TestQueryStatsSkipsOtherStatements(t *testing.T) {
+    ql := &queryLog{}
+
+    // Without stats in the context nothing is counted, and EXPLAIN runs
+    // are never counted
+    ql.observe(context.Background(), "SELECT 1", nil, time.Now())
+    ctx, stats := WithQueryStats(context.Background())
+    ql.observe(context.WithValue(ctx, explainKey{}, true), "SELECT 1", nil, time.Now())
+    if got := stats.Total(); got != 0 {
+        t.Errorf("Total() = %d, want 0", got)
+    }
+}
+
+func TestQueryStatsBoundsDistinctStatements(t *testing.T) {
+    _, stats := WithQueryStats(context.Background())
+    for i := 0; i < maxTrackedQueries+10; i++ {
+        stats.add(fmt.Sprintf("SELECT * FROM t%d", i))
+    }
+    stats.add("SELECT * FROM t0")
+    stats.add("SELECT * FROM t300")
+
+    if got := stats.Total(); got != maxTrackedQueries+12 {
+        t.Errorf("Total() = %d, want every statement counted", got)
+    }
+    if got := len(stats.byQuery); got != maxTrackedQueries {
+        t.Errorf("tracked %d statements, want at most %d", got, maxTrackedQueries)
+    }
+    want := []RepeatedQuery{{Query: "SELECT * FROM t0", Count: 2}}
+    if got := stats.Repeated(2); !reflect.DeepEqual(got, want) {
+        t.Errorf("Repeated(2) = %+v, want %+v", got, want)
+    }
+}
+
+func TestExplainable(t *testing.T) {
+    tests := []struct {
+        query string
+        want  bool
+    }{
+        {"SELECT 1", true},
+        {"  with q AS (SELECT 1) SELECT * FROM q", true},
+        {"INSERT INTO t VALUES ($1)", true},
+        {"UPDATE t SET a = 1", true},
+        {"DELETE FROM t", true},
+        {"BEGIN", false},
+        {"CREATE INDEX i ON t (a)", false},
+        {"", false},
+    }
+
+    for _, tt := range tests {
+        if got := explainable(tt.query); got != tt.want {
+            t.Errorf("explainable(%q) = %v, want %v", tt.query, got, tt.want)
+        }
+    }
+}
@@ -0,0 +1,122 @@
+// Package debugserver serves profiling and runtime state on an internal
+// listener kept apart from the API: net/http/pprof, goroutine dumps, the
+// running config with secrets redacted, connection pool and rate limiter
+// state, and the build. Every request must carry the debug token.
+package debugserver
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	rpprof "runtime/pprof"
+	"strings"
+	"time"
+
+	"github.com/francis/projectx-api/internal/buildinfo"
+	"github.com/francis/projectx-api/internal/config"
+	"github.com/francis/projectx-api/internal/middleware"
+	"github.com/francis/projectx-api/internal/model"
+	"github.com/francis/projectx-api/pkg/logger"
+)
+
+// Options are what the debug endpoints report on.
+type Options struct {
+    Token     string
+    Config    *config.Reloader
+    DB        *sql.DB
+    RateLimit *middleware.RateLimitPolicy
+    Log       logger.Logger
+}
+
+type server struct {
+    opts    Options
+    started time.Time
+}
+
+// Handler returns the debug endpoints. It registers pprof on its own mux,
+// so nothing is exposed through http.DefaultServeMux.
+func Handler(opts Options) http.Handler {
+    s := &server{opts: opts, started: time.Now()}
+
+    mux := http.NewServeMux()
+    mux.HandleFunc("/debug/pprof/", pprof.Index)
+    mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+    mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+    mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+    mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+    mux.HandleFunc("/debug/goroutines", s.goroutines)
+    mux.HandleFunc("/debug/config", s.config)
+    mux.HandleFunc("/debug/db", s.db)
+    mux.HandleFunc("/debug/ratelimit", s.rateLimit)
+    mux.HandleFunc("/debug/build", s.build)
+    return s.authorize(mux)
+}
+
+// authorize rejects requests without the debug token as a bearer token.
+func (s *server) authorize(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+        if !ok || s.opts.Token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.opts.Token)) != 1 {
+            s.opts.Log.Warn("Debug request rejected", "path", r.URL.Path, "remote_addr", r.RemoteAddr)
+            writeJSON(w, http.StatusUnauthorized, model.ErrorResponse("Invalid debug token"))
+            return
+        }
+        s.opts.Log.Info("Debug request", "path", r.URL.Path, "remote_addr", r.RemoteAddr)
+        next.ServeHTTP(w, r)
+    })
+}
+
+// goroutines writes the stack of every goroutine, as a panic would.
+func (s *server) goroutines(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+    rpprof.Lookup("goroutine").WriteTo(w, 2)
+}
+
+func (s *server) config(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/yaml; charset=utf-8")
+    if err := s.opts.Config.Current().WriteYAML(w); err != nil {
+        s.opts.Log.Error("Failed to write config", err)
+    }
+}
+
+func (s *server) db(w http.ResponseWriter, r *http.Request) {
+    stats := s.opts.DB.Stats()
+    writeJSON(w, http.StatusOK, map[string]interface{}{
+        "max_open_connections": stats.MaxOpenConnections,
+        "open_connections":     stats.OpenConnections,
+        "in_use":               stats.InUse,
+        "idle":                 stats.Idle,
+        "wait_count":           stats.WaitCount,
+        "wait_duration_ms":     stats.WaitDuration.Milliseconds(),
+        "max_idle_closed":      stats.MaxIdleClosed,
+        "max_idle_time_closed": stats.MaxIdleTimeClosed,
+        "max_lifetime_closed":  stats.MaxLifetimeClosed,
+    })
+}
+
+func (s *server) rateLimit(w http.ResponseWriter, r *http.Request) {
+    writeJSON(w, http.StatusOK, map[string]interface{}{
+        "ip": s.opts.RateLimit.Stats(),
+    })
+}
+
+func (s *server) build(w http.ResponseWriter, r *http.Request) {
+    writeJSON(w, http.StatusOK, map[string]interface{}{
+        "build":      buildinfo.Get(),
+        "started_at": s.started.UTC().Format(time.RFC3339),
+        "uptime":     time.Since(s.started).Round(time.Second).String(),
+        "goroutines": runtime.NumGoroutine(),
+        "gomaxprocs": runtime.GOMAXPROCS(0),
+    })
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(status)
+    enc := json.NewEncoder(w)
+    enc.SetIndent("", "  ")
+    enc.Encode(v)
+}
@@ -0,0 +1,277 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/francis/projectx-api/internal/apperror"
+	"github.com/francis/projectx-api/internal/model"
+	"github.com/francis/projectx-api/internal/service"
+	"github.com/francis/projectx-api/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+const maxImportSize = 10 << 20 // 10MB
+
+type AdminUserHandler struct {
+    importService *service.UserImportService
+    userService   *service.UserService
+}
+
+func NewAdminUserHandler(importService *service.UserImportService, userService *service.UserService) *AdminUserHandler {
+    return &AdminUserHandler{
+        importService: importService,
+        userService:   userService,
+    }
+}
+
+// ImportUsers accepts a CSV or JSON file either as the raw request body or as
+// the "file" field of a multipart form. With dry_run=true the rows are only
+// validated and a per-row report is returned; otherwise a background job is
+// started and 202 Accepted is returned with the job to poll.
+func (h *AdminUserHandler) ImportUsers(c *gin.Context) {
+    c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+
+    body, format, err := importSource(c)
+    if err != nil {
+        respondError(c, err, "Failed to read import file")
+        return
+    }
+    defer body.Close()
+
+    rows, err := h.importService.ParseRows(body, format)
+    if err != nil {
+        respondError(c, err, "Failed to read import file")
+        return
+    }
+
+    opts := model.ImportOptions{
+        Format:      format,
+        DryRun:      c.Query("dry_run") == "true",
+        SkipInvalid: c.Query("skip_invalid") == "true",
+        SendInvites: c.Query("send_invites") == "true",
+    }
+    if size, err := strconv.Atoi(c.Query("batch_size")); err == nil {
+        opts.BatchSize = size
+    }
+
+    if opts.DryRun {
+        report, err := h.importService.Validate(c.Request.Context(), rows, opts)
+        if err != nil {
+            respondError(c, err, "Failed to validate import")
+            return
+        }
+        c.JSON(http.StatusOK, model.SuccessResponse(report, "Import validated"))
+        return
+    }
+
+    userID := c.GetInt("user_id")
+    job, err := h.importService.StartImport(c.Request.Context(), userID, rows, opts)
+    if err != nil {
+        // The per-row report goes out as details, so the rows can be fixed
+        var validationErr *service.ImportValidationError
+        if errors.As(err, &validationErr) {
+            err = apperror.Wrap(err, apperror.KindValidation, "").WithCode("import_rows_invalid").WithStatus(http.StatusUnprocessableEntity).WithDetails(validationErr.Report)
+        }
+        respondError(c, err, "Failed to start import")
+        return
+    }
+
+    c.JSON(http.StatusAccepted, model.SuccessResponse(job, "Import started"))
+}
+
+func importSource(c *gin.Context) (io.ReadCloser, model.ImportFormat, error) {
+    format := model.ImportFormat(strings.ToLower(c.Query("format")))
+
+    if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+        fileHeader, err := c.FormFile("file")
+        if err != nil {
+            return nil, "", apperror.Validation("multipart upload must contain a \"file\" field").WithCode("missing_import_file")
+        }
+        if format == "" {
+            format = formatFromName(fileHeader.Filename)
+        }
+        file, err := fileHeader.Open()
+        if err != nil {
+            return nil, "", err
+        }
+        return file, format, nil
+    }
+
+    if format == "" {
+        switch c.ContentType() {
+        case "application/json":
+            format = model.ImportFormatJSON
+        case "text/csv":
+            format = model.ImportFormatCSV
+        }
+    }
+    return c.Request.Body, format, nil
+}
+
+func formatFromName(name string) model.ImportFormat {
+    switch {
+    case strings.HasSuffix(strings.ToLower(name), ".json"):
+        return model.ImportFormatJSON
+    case strings.HasSuffix(strings.ToLower(name), ".csv"):
+        return model.ImportFormatCSV
+    }
+    return ""
+}
+
+// ExportUsers streams users matching the query filters as CSV or JSON, in a
+// layout ImportUsers accepts. Rows are written as they are read so large
+// exports use constant memory.
+func (h *AdminUserHandler) ExportUsers(c *gin.Context) {
+    filter, err := parseUserFilter(c)
+    if err != nil {
+        respondError(c, err, "Failed to export users")
+        return
+    }
+    filter.Attributes, err = h.userService.ParseAttributeFilter(c.Request.Context(), c.QueryMap("attr"))
+    if err != nil {
+        respondError(c, err, "Failed to export users")
+        return
+    }
+
+    format := model.ImportFormat(strings.ToLower(c.DefaultQuery("format", "csv")))
+    stamp := time.Now().UTC().Format("20060102-150405")
+
+    switch format {
+    case model.ImportFormatCSV:
+        // Every attribute gets a column, named as the import expects
+        var defs []*model.AttributeDefinition
+        defs, err = h.userService.AttributeDefinitions(c.Request.Context())
+        if err != nil {
+            respondError(c, err, "Failed to export users")
+            return
+        }
+
+        c.Header("Content-Type", "text/csv")
+        c.Header("Content-Disposition", "attachment; filename=users-"+stamp+".csv")
+        c.Status(http.StatusOK)
+
+        header := []string{"id", "email", "username", "first_name", "last_name"}
+        for _, def := range defs {
+            header = append(header, "attr."+def.Key)
+        }
+        header = append(header, "created_at", "updated_at")
+
+        w := csv.NewWriter(c.Writer)
+        w.Write(header)
+        err = h.userService.Stream(c.Request.Context(), filter, func(user *model.User) error {
+            record := []string{
+                strconv.Itoa(user.ID),
+                user.Email,
+                user.Username,
+                user.FirstName,
+                user.LastName,
+            }
+            for _, def := range defs {
+                record = append(record, attributeCell(user.Attributes[def.Key]))
+            }
+            record = append(record, user.CreatedAt.Format(time.RFC3339), user.UpdatedAt.Format(time.RFC3339))
+            w.Write(record)
+            w.Flush()
+            return w.Error()
+        })
+        w.Flush()
+
+    case model.ImportFormatJSON:
+        c.Header("Content-Type", "application/json")
+        c.Header("Content-Disposition", "attachment; filename=users-"+stamp+".json")
+        c.Status(http.StatusOK)
+
+        enc := json.NewEncoder(c.Writer)
+        first := true
+        c.Writer.WriteString("[")
+        err = h.userService.Stream(c.Request.Context(), filter, func(user *model.User) error {
+            if !first {
+                c.Writer.WriteString(",")
+            }
+            first = false
+            if err := enc.Encode(user); err != nil {
+                return err
+            }
+            c.Writer.Flush()
+            return nil
+        })
+        if err == nil {
+            c.Writer.WriteString("]")
+        }
+
+    default:
+        respondError(c, apperror.Validation("format must be csv or json").WithCode("unsupported_export_format"), "")
+        return
+    }
+
+    // Headers are already sent, so the failure is logged and the
+    // connection dropped: the client sees a truncated transfer instead of
+    // a file that looks complete
+    if err != nil {
+        logger.FromContext(c.Request.Context()).Error("User export aborted", err)
+        abortResponse(c)
+    }
+}
+
+// attributeCell formats an attribute value the way a CSV import reads it.
+func attributeCell(value interface{}) string {
+    switch v := value.(type) {
+    case nil:
+        return ""
+    case string:
+        return v
+    case float64:
+        return strconv.FormatFloat(v, 'f', -1, 64)
+    default:
+        return fmt.Sprint(v)
+    }
+}
+
+// abortResponse closes the connection under a response that has already
+// started.
+func abortResponse(c *gin.Context) {
+    conn, _, err := c.Writer.Hijack()
+    if err != nil {
+        logger.FromContext(c.Request.Context()).Warn("Failed to abort response", "error", err)
+        return
+    }
+    conn.Close()
+}
+
+func parseUserFilter(c *gin.Context) (model.UserFilter, error) {
+    filter := model.UserFilter{Email: c.Query("email")}
+
+    if v := c.Query("created_after"); v != "" {
+        t, err := time.Parse(time.RFC3339, v)
+        if err != nil {
+            return filter, apperror.Validation("created_after must be an RFC 3339 timestamp").WithCode("invalid_filter")
+        }
+        filter.CreatedAfter = &t
+    }
+    if v := c.Query("created_before"); v != "" {
+        t, err := time.Parse(time.RFC3339, v)
+        if err != nil {
+            return filter, apperror.Validation("created_before must be an RFC 3339 timestamp").WithCode("invalid_filter")
+        }
+        filter.CreatedBefore = &t
+    }
+    return filter, nil
+}
+
+func (h *AdminUserHandler) GetJob(c *gin.Context) {
+    job, err := h.importService.GetJob(c.Request.Context(), c.Param("id"))
+    if err != nil {
+        respondError(c, err, "Failed to get job")
+        return
+    }
+
+    c.JSON(http.StatusOK, model.SuccessResponse(job, "Job retrieved successfully"))
+}
@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/francis/projectx-api/internal/model"
+	"github.com/francis/projectx-api/internal/repository"
+	"github.com/francis/projectx-api/internal/repository/memory"
+	"github.com/francis/projectx-api/internal/requestid"
+	"github.com/francis/projectx-api/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// staticAttributeRepo serves a fixed set of attribute definitions.
+type staticAttributeRepo struct {
+    repository.AttributeRepository
+    defs []*model.AttributeDefinition
+}
+
+func (r staticAttributeRepo) List(ctx context.Context) ([]*model.AttributeDefinition, error) {
+    return r.defs, nil
+}
+
+// failingStreamRepo fails a stream after its first user.
+type failingStreamRepo struct {
+    repository.UserRepository
+}
+
+func (r failingStreamRepo) Stream(ctx context.Context, filter model.UserFilter, fn func(*model.User) error) error {
+    if err := fn(&model.User{ID: 1, Email: "jane@example.com", FirstName: "Jane", LastName: "Doe"}); err != nil {
+        return err
+    }
+    return errors.New("connection reset")
+}
+
+func newAdminUserServer(t *testing.T, userRepo repository.UserRepository) (*httptest.Server, *service.UserImportService) {
+    t.Helper()
+
+    attributes := service.NewAttributeService(staticAttributeRepo{defs: []*model.AttributeDefinition{
+        {Key: "org", Type: model.AttributeTypeString},
+        {Key: "seats", Type: model.AttributeTypeNumber},
+        {Key: "beta", Type: model.AttributeTypeBoolean},
+    }})
+    imports := service.NewUserImportService(nil, userRepo, nil, nil, attributes, nil)
+    h := NewAdminUserHandler(imports, service.NewUserService(userRepo, attributes))
+
+    gin.SetMode(gin.TestMode)
+    router := gin.New()
+    router.GET("/export", h.ExportUsers)
+    router.POST("/import", func(c *gin.Context) {
+        requestid.Set(c, "req-123")
+        h.ImportUsers(c)
+    })
+    srv := httptest.NewServer(router)
+    t.Cleanup(srv.Close)
+    return srv, imports
+}
+
+func TestExportUsersRoundTrips(t *testing.T) {
+    repo := memory.NewUserRepository()
+    user := &model.User{
+        Email:      "jane@example.com",
+        Username:   "jane",
+        FirstName:  "Jane",
+        LastName:   "Doe",
+        Attributes: model.Attributes{"org": "acme", "seats": 2.5, "beta": true},
+    }
+    if err := repo.Create(context.Background(), user); err != nil {
+        t.Fatalf("Create: %v", err)
+    }
+    srv, imports := newAdminUserServer(t, repo)
+
+    tests := []struct {
+        format model.ImportFormat
+        want   model.Attributes
+    }{
+        // CSV cells stay strings until the import types them
+        {model.ImportFormatCSV, model.Attributes{"org": "acme", "seats": "2.5", "beta": "true"}},
+        {model.ImportFormatJSON, model.Attributes{"org": "acme", "seats": 2.5, "beta": true}},
+    }
+
+    for _, tt := range tests {
+        t.Run(string(tt.format), func(t *testing.T) {
+            resp, err := http.Get(srv.URL + "/export?format=" + string(tt.format))
+            if err != nil {
+                t.Fatalf("GET: %v", err)
+            }
+            defer resp.Body.Close()
+
+            rows, err := imports.ParseRows(resp.Body, tt.format)
+            if err != nil {
+                t.Fatalf("ParseRows: %v", err)
+            }
+            want := []model.ImportUserRow{{
+                Email:      "jane@example.com",
+                Username:   "jane",
+                FirstName:  "Jane",
+                LastName:   "Doe",
+                Attributes: tt.want,
+            }}
+            if !reflect.DeepEqual(rows, want) {
+                t.Errorf("re-imported rows = %+v, want %+v", rows, want)
+            }
+        })
+    }
+}
+
+func TestExportUsersTruncatedOnError(t *testing.T) {
+    srv, _ := newAdminUserServer(t, failingStreamRepo{})
+
+    for _, format := range []string{"csv", "json"} {
+        t.Run(format, func(t *testing.T) {
+            resp, err := http.Get(srv.URL + "/export?format=" + format)
+            if err != nil {
+                t.Fatalf("GET: %v", err)
+            }
+            defer resp.Body.Close()
+
+            body, err := io.ReadAll(resp.Body)
+            if !errors.Is(err, io.ErrUnexpectedEOF) {
+                t.Errorf("reading body: err = %v, want io.ErrUnexpectedEOF", err)
+            }
+            if format == "json" && strings.HasSuffix(strings.TrimSpace(string(body)), "]") {
+                t.Errorf("truncated export closes the JSON array: %s", body)
+            }
+        })
+    }
+}
+
+func TestImportUsersRejectsInvalidRows(t *testing.T) {
+    srv, _ := newAdminUserServer(t, memory.NewUserRepository())
+    csv := "email,first_name,last_name,password\n" +
+        "jane@example.com,Jane,Doe,longenough\n" +
+        "not-an-email,John,Doe,longenough\n"
+
+    tests := []struct {
+        accept string
+        member string
+    }{
+        {"application/json", "data"},
+        {model.ProblemContentType, "details"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.accept, func(t *testing.T) {
+            req, err := http.NewRequest(http.MethodPost, srv.URL+"/import", strings.NewReader(csv))
+            if err != nil {
+                t.Fatal(err)
+            }
+            req.Header.Set("Content-Type", "text/csv")
+            req.Header.Set("Accept", tt.accept)
+            resp, err := http.DefaultClient.Do(req)
+            if err != nil {
+                t.Fatalf("POST: %v", err)
+            }
+            defer resp.Body.Close()
+
+            if resp.StatusCode != http.StatusUnprocessableEntity {
+                t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusUnprocessableEntity)
+            }
+            var body struct {
+                Code      string              `json:"code"`
+                RequestID string              `json:"request_id"`
+                Data      *model.ImportReport `json:"data"`
+                Details   *model.ImportReport `json:"details"`
+            }
+            if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+                t.Fatalf("decode: %v", err)
+            }
+            if body.Code != "import_rows_invalid" || body.RequestID != "req-123" {
+                t.Errorf("code = %q, request_id = %q, want import_rows_invalid and req-123", body.Code, body.RequestID)
+            }
+            report := body.Data
+            if tt.member == "details" {
+                report = body.Details
+            }
+            if report == nil || report.Invalid != 1 || len(report.Errors) != 1 || report.Errors[0].Row != 2 {
+                t.Errorf("%s = %+v, want a report naming row 2", tt.member, report)
+            }
+        })
+    }
+}
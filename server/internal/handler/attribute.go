@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/francis/projectx-api/internal/model"
+	"github.com/francis/projectx-api/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type AttributeHandler struct {
+    attributeService *service.AttributeService
+}
+
+func NewAttributeHandler(attributeService *service.AttributeService) *AttributeHandler {
+    return &AttributeHandler{
+        attributeService: attributeService,
+    }
+}
+
+func (h *AttributeHandler) ListAttributes(c *gin.Context) {
+    defs, err := h.attributeService.List(c.Request.Context())
+    if err != nil {
+        respondError(c, err, "Failed to list attributes")
+        return
+    }
+
+    c.JSON(http.StatusOK, model.SuccessResponse(defs, "Attributes retrieved successfully"))
+}
+
+func (h *AttributeHandler) CreateAttribute(c *gin.Context) {
+    var def model.AttributeDefinition
+    if err := c.ShouldBindJSON(&def); err != nil {
+        respondError(c, errInvalidBody, "")
+        return
+    }
+
+    if err := h.attributeService.Create(c.Request.Context(), &def); err != nil {
+        respondError(c, err, "Failed to create attribute")
+        return
+    }
+
+    c.JSON(http.StatusCreated, model.SuccessResponse(def, "Attribute created successfully"))
+}
+
+func (h *AttributeHandler) UpdateAttribute(c *gin.Context) {
+    var def model.AttributeDefinition
+    if err := c.ShouldBindJSON(&def); err != nil {
+        respondError(c, errInvalidBody, "")
+        return
+    }
+
+    def.Key = c.Param("key")
+    if err := h.attributeService.Update(c.Request.Context(), &def); err != nil {
+        respondError(c, err, "Failed to update attribute")
+        return
+    }
+
+    c.JSON(http.StatusOK, model.SuccessResponse(def, "Attribute updated successfully"))
+}
+
+// DeleteAttribute removes the definition only. Values already stored on
+// users are kept but are rejected on the user's next profile update.
+func (h *AttributeHandler) DeleteAttribute(c *gin.Context) {
+    if err := h.attributeService.Delete(c.Request.Context(), c.Param("key")); err != nil {
+        respondError(c, err, "Failed to delete attribute")
+        return
+    }
+
+    c.JSON(http.StatusOK, model.SuccessResponse(nil, "Attribute deleted successfully"))
+}
@@ -3,9 +3,9 @@ package handler
 import (
 	"net/http"
 
-	"github.com/francis/projectx-api/pkg/logger"
 	"github.com/francis/projectx-api/pkg/validator"
 
+	"github.com/francis/projectx-api/internal/apperror"
 	"github.com/francis/projectx-api/internal/model"
 	"github.com/francis/projectx-api/internal/service"
 	"github.com/gin-gonic/gin"
@@ -13,32 +13,29 @@ import (
 
 type AuthHandler struct {
     authService *service.AuthService
-    logger      logger.Logger
 }
 
-func NewAuthHandler(authService *service.AuthService, logger logger.Logger) *AuthHandler {
+func NewAuthHandler(authService *service.AuthService) *AuthHandler {
     return &AuthHandler{
         authService: authService,
-        logger:      logger,
     }
 }
 
 func (h *AuthHandler) Register(c *gin.Context) {
     var req model.CreateUserRequest
     if err := c.ShouldBindJSON(&req); err != nil {
-        c.JSON(http.StatusBadRequest, model.ErrorResponse("Invalid request body"))
+        respondError(c, errInvalidBody, "")
         return
     }
 
     if err := validator.Validate(&req); err != nil {
-        c.JSON(http.StatusBadRequest, model.ErrorResponse(err.Error()))
+        respondError(c, apperror.Invalid(err), "")
         return
     }
 
     user, err := h.authService.Register(c.Request.Context(), &req)
     if err != nil {
-        h.logger.Error("Failed to register user", err)
-        c.JSON(http.StatusBadRequest, model.ErrorResponse(err.Error()))
+        respondError(c, err, "Failed to register user")
         return
     }
 
@@ -48,19 +45,18 @@ func (h *AuthHandler) Register(c *gin.Context) {
 func (h *AuthHandler) Login(c *gin.Context) {
     var req model.LoginRequest
     if err := c.ShouldBindJSON(&req); err != nil {
-        c.JSON(http.StatusBadRequest, model.ErrorResponse("Invalid request body"))
+        respondError(c, errInvalidBody, "")
         return
     }
 
     if err := validator.Validate(&req); err != nil {
-        c.JSON(http.StatusBadRequest, model.ErrorResponse(err.Error()))
+        respondError(c, apperror.Invalid(err), "")
         return
     }
 
     response, err := h.authService.Login(c.Request.Context(), &req)
     if err != nil {
-        h.logger.Error("Failed to login user", err)
-        c.JSON(http.StatusUnauthorized, model.ErrorResponse(err.Error()))
+        respondError(c, err, "Failed to login user")
         return
     }
 
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/francis/projectx-api/internal/apperror"
+	"github.com/francis/projectx-api/internal/httperr"
+	"github.com/francis/projectx-api/internal/model"
+	"github.com/francis/projectx-api/internal/requestid"
+	"github.com/francis/projectx-api/internal/service"
+	"github.com/francis/projectx-api/pkg/validator"
+	"github.com/gin-gonic/gin"
+)
+
+// ClientHandler serves the auth contract of the bundled React client so it
+// can run against this server unmodified. The regular /api/v1 endpoints are
+// unaffected.
+type ClientHandler struct {
+    authService *service.AuthService
+    userService *service.UserService
+}
+
+func NewClientHandler(authService *service.AuthService, userService *service.UserService) *ClientHandler {
+    return &ClientHandler{
+        authService: authService,
+        userService: userService,
+    }
+}
+
+// Login accepts {username, password}, where username may also be an email,
+// and returns {authToken, user}.
+func (h *ClientHandler) Login(c *gin.Context) {
+    var req model.ClientLoginRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        h.respondError(c, errInvalidBody, "")
+        return
+    }
+
+    if err := validator.Validate(&req); err != nil {
+        h.respondError(c, apperror.Invalid(err), "")
+        return
+    }
+
+    response, err := h.authService.Login(c.Request.Context(), &model.LoginRequest{
+        Username: req.Username,
+        Password: req.Password,
+    })
+    if errors.Is(err, apperror.ErrUnauthorized) {
+        c.JSON(http.StatusUnauthorized, model.ClientErrorResponse{Message: "Invalid username or password"})
+        return
+    }
+    if err != nil {
+        h.respondError(c, err, "Failed to login user")
+        return
+    }
+
+    c.JSON(http.StatusOK, model.ClientLoginResponse{
+        AuthToken: response.Token,
+        User:      model.NewClientUser(&response.User, response.Roles),
+    })
+}
+
+func (h *ClientHandler) GetProfile(c *gin.Context) {
+    user, err := h.userService.GetByID(c.Request.Context(), c.GetInt("user_id"))
+    if err != nil {
+        h.respondError(c, err, "Failed to get user profile")
+        return
+    }
+
+    c.JSON(http.StatusOK, model.ClientProfileResponse{
+        User: model.NewClientUser(user, c.GetStringSlice("roles")),
+    })
+}
+
+// respondError is the client's version of the package respondError: same
+// statuses, but in the error shape the React client reads.
+func (h *ClientHandler) respondError(c *gin.Context, err error, fallback string) {
+    mapped := httperr.Map(err, fallback)
+    httperr.Log(c, err, mapped, fallback)
+    httperr.LocalizeFields(c, &mapped)
+    c.JSON(mapped.Status, model.ClientErrorResponse{
+        Message:   mapped.Message,
+        RequestID: requestid.Get(c),
+    })
+}
@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/francis/projectx-api/internal/apperror"
+	"github.com/francis/projectx-api/internal/config"
+	"github.com/francis/projectx-api/internal/model"
+	"github.com/francis/projectx-api/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+type ConfigHandler struct {
+    reloader *config.Reloader
+}
+
+func NewConfigHandler(reloader *config.Reloader) *ConfigHandler {
+    return &ConfigHandler{
+        reloader: reloader,
+    }
+}
+
+// ReloadConfig re-reads the configuration, as SIGHUP does. An invalid config
+// is rejected with 422 and the running config stays in effect.
+func (h *ConfigHandler) ReloadConfig(c *gin.Context) {
+    result, err := h.reloader.Reload()
+    if err != nil {
+        logger.FromContext(c.Request.Context()).Warn("Config reload rejected", "error", err.Error())
+        respondError(c, apperror.Wrap(err, apperror.KindValidation, "").WithCode("invalid_config").WithStatus(http.StatusUnprocessableEntity), "")
+        return
+    }
+
+    logger.FromContext(c.Request.Context()).Info("Config reloaded", "changed", result.Changed, "restart_required", result.RestartRequired)
+    c.JSON(http.StatusOK, model.SuccessResponse(result, "Configuration reloaded"))
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/francis/projectx-api/internal/config"
+	"github.com/francis/projectx-api/internal/model"
+	"github.com/francis/projectx-api/internal/requestid"
+	"github.com/gin-gonic/gin"
+)
+
+func TestReloadConfigRejected(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "config.yaml")
+    if err := os.WriteFile(path, []byte("http:\n  port: [\n"), 0o600); err != nil {
+        t.Fatal(err)
+    }
+    h := NewConfigHandler(config.NewReloader(path, config.Default()))
+
+    gin.SetMode(gin.TestMode)
+    router := gin.New()
+    router.POST("/reload", func(c *gin.Context) {
+        requestid.Set(c, "req-123")
+        h.ReloadConfig(c)
+    })
+
+    for _, accept := range []string{"application/json", model.ProblemContentType} {
+        t.Run(accept, func(t *testing.T) {
+            w := httptest.NewRecorder()
+            req := httptest.NewRequest(http.MethodPost, "/reload", nil)
+            req.Header.Set("Accept", accept)
+            router.ServeHTTP(w, req)
+
+            if w.Code != http.StatusUnprocessableEntity {
+                t.Errorf("status = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+            }
+            if got := w.Header().Get("Content-Type"); accept == model.ProblemContentType && got != model.ProblemContentType {
+                t.Errorf("Content-Type = %q, want %q", got, model.ProblemContentType)
+            }
+            var body struct {
+                Code      string `json:"code"`
+                RequestID string `json:"request_id"`
+            }
+            if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+                t.Fatalf("decode: %v", err)
+            }
+            if body.Code != "invalid_config" || body.RequestID != "req-123" {
+                t.Errorf("body = %+v, want code invalid_config and the request ID", body)
+            }
+        })
+    }
+}
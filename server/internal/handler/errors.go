@@ -0,0 +1,16 @@
+package handler
+
+import (
+	"github.com/francis/projectx-api/internal/apperror"
+	"github.com/francis/projectx-api/internal/httperr"
+	"github.com/gin-gonic/gin"
+)
+
+// errInvalidBody is reported when the request body cannot be decoded.
+var errInvalidBody = apperror.Validation("Invalid request body").WithCode("invalid_body")
+
+// respondError writes the error response for err, logging internal errors
+// with fallback as the message.
+func respondError(c *gin.Context, err error, fallback string) {
+    httperr.Respond(c, err, fallback)
+}
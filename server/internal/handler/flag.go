@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/francis/projectx-api/internal/model"
+	"github.com/francis/projectx-api/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type FlagHandler struct {
+    flagService *service.FlagService
+}
+
+func NewFlagHandler(flagService *service.FlagService) *FlagHandler {
+    return &FlagHandler{
+        flagService: flagService,
+    }
+}
+
+// GetMyFlags returns every flag evaluated for the caller, for clients that
+// show or hide features themselves.
+func (h *FlagHandler) GetMyFlags(c *gin.Context) {
+    c.JSON(http.StatusOK, model.SuccessResponse(h.flagService.Evaluate(c.Request.Context()), "Flags retrieved successfully"))
+}
+
+func (h *FlagHandler) ListFlags(c *gin.Context) {
+    flags, err := h.flagService.List(c.Request.Context())
+    if err != nil {
+        respondError(c, err, "Failed to list flags")
+        return
+    }
+
+    c.JSON(http.StatusOK, model.SuccessResponse(flags, "Flags retrieved successfully"))
+}
+
+func (h *FlagHandler) GetFlag(c *gin.Context) {
+    flag, err := h.flagService.Get(c.Request.Context(), c.Param("name"))
+    if err != nil {
+        respondError(c, err, "Failed to get flag")
+        return
+    }
+
+    c.JSON(http.StatusOK, model.SuccessResponse(flag, "Flag retrieved successfully"))
+}
+
+func (h *FlagHandler) CreateFlag(c *gin.Context) {
+    var flag model.FeatureFlag
+    if err := c.ShouldBindJSON(&flag); err != nil {
+        respondError(c, errInvalidBody, "")
+        return
+    }
+
+    if err := h.flagService.Create(c.Request.Context(), &flag); err != nil {
+        respondError(c, err, "Failed to create flag")
+        return
+    }
+
+    c.JSON(http.StatusCreated, model.SuccessResponse(flag, "Flag created successfully"))
+}
+
+func (h *FlagHandler) UpdateFlag(c *gin.Context) {
+    var flag model.FeatureFlag
+    if err := c.ShouldBindJSON(&flag); err != nil {
+        respondError(c, errInvalidBody, "")
+        return
+    }
+
+    flag.Name = c.Param("name")
+    if err := h.flagService.Update(c.Request.Context(), &flag); err != nil {
+        respondError(c, err, "Failed to update flag")
+        return
+    }
+
+    c.JSON(http.StatusOK, model.SuccessResponse(flag, "Flag updated successfully"))
+}
+
+func (h *FlagHandler) DeleteFlag(c *gin.Context) {
+    if err := h.flagService.Delete(c.Request.Context(), c.Param("name")); err != nil {
+        respondError(c, err, "Failed to delete flag")
+        return
+    }
+
+    c.JSON(http.StatusOK, model.SuccessResponse(nil, "Flag deleted successfully"))
+}
@@ -4,36 +4,98 @@ import (
 	"database/sql"
 	"net/http"
 
+	"github.com/francis/projectx-api/internal/buildinfo"
+	"github.com/francis/projectx-api/internal/database"
+	"github.com/francis/projectx-api/internal/health"
 	"github.com/francis/projectx-api/internal/model"
 	"github.com/francis/projectx-api/pkg/logger"
 	"github.com/gin-gonic/gin"
 )
 
 type HealthHandler struct {
-    db     *sql.DB
-    logger logger.Logger
+    registry *health.Registry
+    db       *sql.DB
 }
 
-func NewHealthHandler(db *sql.DB, logger logger.Logger) *HealthHandler {
-    return &HealthHandler{
-        db:     db,
-        logger: logger,
+// NewHealthHandler creates the probe handlers. /livez only says the
+// process is serving HTTP, /readyz whether it should get traffic and
+// /health gives every check, the schema version and the build for people
+// and dashboards.
+func NewHealthHandler(registry *health.Registry, db *sql.DB) *HealthHandler {
+    return &HealthHandler{registry: registry, db: db}
+}
+
+// Liveness never looks at dependencies: restarting the process does not
+// fix a database outage.
+func (h *HealthHandler) Liveness(c *gin.Context) {
+    c.JSON(http.StatusOK, model.SuccessResponse(gin.H{"status": "alive"}, "Service is alive"))
+}
+
+// Readiness fails while a critical check fails or shutdown is in progress.
+func (h *HealthHandler) Readiness(c *gin.Context) {
+    if h.registry.ShuttingDown() {
+        c.JSON(http.StatusServiceUnavailable, model.APIResponse{
+            Success: false,
+            Error:   "Service is shutting down",
+            Data:    gin.H{"status": "shutting_down"},
+        })
+        return
+    }
+
+    report := h.run(c)
+    if !report.Ready() {
+        c.JSON(http.StatusServiceUnavailable, model.APIResponse{
+            Success: false,
+            Error:   "Service is not ready",
+            Data:    report,
+        })
+        return
     }
+    c.JSON(http.StatusOK, model.SuccessResponse(report, "Service is ready"))
 }
 
 func (h *HealthHandler) HealthCheck(c *gin.Context) {
-    // Check database connection
-    if err := h.db.Ping(); err != nil {
-        h.logger.Error("Database health check failed", err)
-        c.JSON(http.StatusServiceUnavailable, model.ErrorResponse("Database connection failed"))
-        return
+    report := h.run(c)
+    response := gin.H{
+        "status":        report.Status,
+        "shutting_down": report.ShuttingDown,
+        "checks":        report.Checks,
+        "build":         buildinfo.Get(),
     }
 
-    response := map[string]interface{}{
-        "status":   "healthy",
-        "database": "connected",
-        "version":  "1.0.0",
+    // The schema is reported whatever schema_check says; the check only
+    // decides whether a mismatch makes the service unhealthy
+    schema, err := database.GetMigrationStatus(c.Request.Context(), h.db)
+    if err != nil {
+        logger.FromContext(c.Request.Context()).Error("Failed to read database schema version", err)
+    } else {
+        response["schema"] = schema
+        if !schema.Current() {
+            response["schema_problem"] = schema.Problem()
+            if report.Status == health.StatusHealthy {
+                response["status"] = health.StatusDegraded
+            }
+        }
     }
 
+    if !report.Ready() {
+        c.JSON(http.StatusServiceUnavailable, model.APIResponse{
+            Success: false,
+            Error:   "Service is unhealthy",
+            Data:    response,
+        })
+        return
+    }
     c.JSON(http.StatusOK, model.SuccessResponse(response, "Service is healthy"))
+}
+
+func (h *HealthHandler) run(c *gin.Context) health.Report {
+    report := h.registry.Run(c.Request.Context())
+    log := logger.FromContext(c.Request.Context())
+    for _, res := range report.Checks {
+        if res.Error != "" {
+            log.Warn("Health check failed", "check", res.Name, "critical", res.Critical, "error", res.Error)
+        }
+    }
+    return report
 }
\ No newline at end of file
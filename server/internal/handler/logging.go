@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/francis/projectx-api/internal/apperror"
+	"github.com/francis/projectx-api/internal/model"
+	"github.com/francis/projectx-api/pkg/logger"
+	"github.com/francis/projectx-api/pkg/validator"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+    defaultLogOverrideTTL = 15 * time.Minute
+    // maxLogOverrideTTL keeps a forgotten override from leaving debug
+    // logging on for good.
+    maxLogOverrideTTL = 24 * time.Hour
+)
+
+type LogLevelHandler struct {
+    control *logger.Control
+}
+
+func NewLogLevelHandler(control *logger.Control) *LogLevelHandler {
+    return &LogLevelHandler{
+        control: control,
+    }
+}
+
+func (h *LogLevelHandler) GetLogLevel(c *gin.Context) {
+    c.JSON(http.StatusOK, model.SuccessResponse(h.state(), "Log level retrieved successfully"))
+}
+
+// SetLogLevel overrides the configured level until the TTL runs out. The
+// configured level itself only changes with the config.
+func (h *LogLevelHandler) SetLogLevel(c *gin.Context) {
+    var req model.LogLevelRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        respondError(c, errInvalidBody, "")
+        return
+    }
+
+    if err := validator.Validate(&req); err != nil {
+        respondError(c, apperror.Invalid(err), "")
+        return
+    }
+
+    ttl := defaultLogOverrideTTL
+    if req.TTL != "" {
+        parsed, err := time.ParseDuration(req.TTL)
+        if err != nil || parsed <= 0 || parsed > maxLogOverrideTTL {
+            respondError(c, apperror.Validation("ttl must be a duration between 1s and %s", maxLogOverrideTTL).WithCode("invalid_ttl"), "")
+            return
+        }
+        ttl = parsed
+    }
+
+    h.control.SetOverride(logger.Override{
+        Level:     req.Level,
+        Module:    req.Module,
+        UserID:    req.UserID,
+        ExpiresAt: time.Now().Add(ttl),
+    })
+    logger.FromContext(c.Request.Context()).Info("Log level overridden", "level", req.Level, "module", req.Module, "target_user_id", req.UserID, "ttl", ttl.String())
+
+    c.JSON(http.StatusOK, model.SuccessResponse(h.state(), "Log level updated successfully"))
+}
+
+// ResetLogLevel drops every override, going back to the configured level.
+func (h *LogLevelHandler) ResetLogLevel(c *gin.Context) {
+    h.control.ClearOverrides()
+    logger.FromContext(c.Request.Context()).Info("Log level overrides cleared")
+
+    c.JSON(http.StatusOK, model.SuccessResponse(h.state(), "Log level reset successfully"))
+}
+
+func (h *LogLevelHandler) state() model.LogLevelState {
+    state := model.LogLevelState{
+        Level:     strings.ToLower(h.control.Level().String()),
+        Overrides: []model.LogLevelOverride{},
+    }
+    for _, o := range h.control.Overrides() {
+        state.Overrides = append(state.Overrides, model.LogLevelOverride{
+            Level:     o.Level,
+            Module:    o.Module,
+            UserID:    o.UserID,
+            ExpiresAt: o.ExpiresAt,
+        })
+    }
+    return state
+}
@@ -3,36 +3,38 @@ package handler
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
+	"github.com/francis/projectx-api/internal/apperror"
 	"github.com/francis/projectx-api/internal/model"
 	"github.com/francis/projectx-api/internal/service"
-	"github.com/francis/projectx-api/pkg/logger"
 	"github.com/gin-gonic/gin"
 )
 
+// errNotAuthenticated is reported when a route that needs a user is reached
+// without AuthMiddleware having identified one.
+var errNotAuthenticated = apperror.Unauthorized("User not authenticated").WithCode("not_authenticated")
+
 type UserHandler struct {
     userService *service.UserService
-    logger      logger.Logger
 }
 
-func NewUserHandler(userService *service.UserService, logger logger.Logger) *UserHandler {
+func NewUserHandler(userService *service.UserService) *UserHandler {
     return &UserHandler{
         userService: userService,
-        logger:      logger,
     }
 }
 
 func (h *UserHandler) GetProfile(c *gin.Context) {
     userID, exists := c.Get("user_id")
     if !exists {
-        c.JSON(http.StatusUnauthorized, model.ErrorResponse("User not authenticated"))
+        respondError(c, errNotAuthenticated, "")
         return
     }
 
     user, err := h.userService.GetByID(c.Request.Context(), userID.(int))
     if err != nil {
-        h.logger.Error("Failed to get user profile", err)
-        c.JSON(http.StatusNotFound, model.ErrorResponse("User not found"))
+        respondError(c, err, "Failed to get user profile")
         return
     }
 
@@ -42,20 +44,19 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 func (h *UserHandler) UpdateProfile(c *gin.Context) {
     userID, exists := c.Get("user_id")
     if !exists {
-        c.JSON(http.StatusUnauthorized, model.ErrorResponse("User not authenticated"))
+        respondError(c, errNotAuthenticated, "")
         return
     }
 
     var updateReq model.User
     if err := c.ShouldBindJSON(&updateReq); err != nil {
-        c.JSON(http.StatusBadRequest, model.ErrorResponse("Invalid request body"))
+        respondError(c, errInvalidBody, "")
         return
     }
 
     updateReq.ID = userID.(int)
     if err := h.userService.Update(c.Request.Context(), &updateReq); err != nil {
-        h.logger.Error("Failed to update user profile", err)
-        c.JSON(http.StatusInternalServerError, model.ErrorResponse("Failed to update profile"))
+        respondError(c, err, "Failed to update profile")
         return
     }
 
@@ -73,12 +74,44 @@ func (h *UserHandler) GetUsers(c *gin.Context) {
         limit = 10
     }
 
-    users, err := h.userService.GetUsers(c.Request.Context(), page, limit)
+    attributes, err := h.userService.ParseAttributeFilter(c.Request.Context(), c.QueryMap("attr"))
+    if err != nil {
+        respondError(c, err, "Failed to get users")
+        return
+    }
+    filter := model.UserFilter{Attributes: attributes}
+
+    users, err := h.userService.GetUsers(c.Request.Context(), filter, page, limit)
     if err != nil {
-        h.logger.Error("Failed to get users", err)
-        c.JSON(http.StatusInternalServerError, model.ErrorResponse("Failed to get users"))
+        respondError(c, err, "Failed to get users")
         return
     }
 
     c.JSON(http.StatusOK, model.SuccessResponse(users, "Users retrieved successfully"))
+}
+
+func (h *UserHandler) SearchUsers(c *gin.Context) {
+    query := strings.TrimSpace(c.Query("q"))
+    if query == "" {
+        respondError(c, apperror.Validation("Query parameter q is required").WithCode("missing_query"), "")
+        return
+    }
+
+    page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+    limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+    if page < 1 {
+        page = 1
+    }
+    if limit < 1 || limit > 100 {
+        limit = 10
+    }
+
+    results, err := h.userService.Search(c.Request.Context(), query, page, limit)
+    if err != nil {
+        respondError(c, err, "Failed to search users")
+        return
+    }
+
+    c.JSON(http.StatusOK, model.SuccessResponse(results, "Users retrieved successfully"))
 }
\ No newline at end of file
@@ -0,0 +1,164 @@
+// Package health runs the dependency checks behind the readiness and
+// health endpoints. Components register a check with its own timeout and
+// say whether the service can serve without it.
+package health
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Statuses reported for a check and for the service as a whole.
+const (
+    StatusHealthy   = "healthy"
+    StatusDegraded  = "degraded"
+    StatusUnhealthy = "unhealthy"
+)
+
+// defaultTimeout applies to checks registered without one.
+const defaultTimeout = 2 * time.Second
+
+// Check is a dependency probe. A failing Critical check makes the service
+// unhealthy and not ready; any other failing check only degrades it.
+type Check struct {
+    Name     string
+    Timeout  time.Duration
+    Critical bool
+    Check    func(ctx context.Context) error
+}
+
+// Result is the outcome of one check.
+type Result struct {
+    Name       string `json:"name"`
+    Status     string `json:"status"`
+    Critical   bool   `json:"critical"`
+    Error      string `json:"error,omitempty"`
+    DurationMs int64  `json:"duration_ms"`
+}
+
+// Report is the outcome of every check.
+type Report struct {
+    Status       string   `json:"status"`
+    ShuttingDown bool     `json:"shutting_down,omitempty"`
+    Checks       []Result `json:"checks"`
+}
+
+// Ready reports whether the service should receive traffic.
+func (r Report) Ready() bool {
+    return !r.ShuttingDown && r.Status != StatusUnhealthy
+}
+
+// Registry holds the registered checks and whether shutdown has begun.
+type Registry struct {
+    mu           sync.RWMutex
+    checks       []Check
+    shuttingDown atomic.Bool
+}
+
+func NewRegistry() *Registry {
+    return &Registry{}
+}
+
+// Register adds a check, replacing any with the same name.
+func (r *Registry) Register(c Check) {
+    if c.Timeout <= 0 {
+        c.Timeout = defaultTimeout
+    }
+
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    for i, existing := range r.checks {
+        if existing.Name == c.Name {
+            r.checks[i] = c
+            return
+        }
+    }
+    r.checks = append(r.checks, c)
+}
+
+// SetShuttingDown marks the service as draining, so readiness fails and
+// load balancers stop sending new requests before the listener closes.
+func (r *Registry) SetShuttingDown() {
+    r.shuttingDown.Store(true)
+}
+
+// ShuttingDown reports whether SetShuttingDown has been called.
+func (r *Registry) ShuttingDown() bool {
+    return r.shuttingDown.Load()
+}
+
+// Run runs every check concurrently, each bounded by its own timeout.
+func (r *Registry) Run(ctx context.Context) Report {
+    r.mu.RLock()
+    checks := append([]Check(nil), r.checks...)
+    r.mu.RUnlock()
+
+    results := make([]Result, len(checks))
+    var wg sync.WaitGroup
+    for i, c := range checks {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            results[i] = run(ctx, c)
+        }()
+    }
+    wg.Wait()
+
+    sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+
+    report := Report{
+        Status:       StatusHealthy,
+        ShuttingDown: r.ShuttingDown(),
+        Checks:       results,
+    }
+    for _, res := range results {
+        if res.Status == StatusHealthy {
+            continue
+        }
+        if res.Critical {
+            report.Status = StatusUnhealthy
+        } else if report.Status == StatusHealthy {
+            report.Status = StatusDegraded
+        }
+    }
+    return report
+}
+
+func run(ctx context.Context, c Check) Result {
+    ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+    defer cancel()
+
+    start := time.Now()
+    // A check that ignores its context still cannot hold up the report
+    done := make(chan error, 1)
+    go func() { done <- c.Check(ctx) }()
+
+    var err error
+    select {
+    case err = <-done:
+    case <-ctx.Done():
+        err = ctx.Err()
+    }
+
+    res := Result{
+        Name:       c.Name,
+        Status:     StatusHealthy,
+        Critical:   c.Critical,
+        DurationMs: time.Since(start).Milliseconds(),
+    }
+    if err != nil {
+        res.Status = StatusUnhealthy
+        if !c.Critical {
+            res.Status = StatusDegraded
+        }
+        res.Error = err.Error()
+        if errors.Is(err, context.DeadlineExceeded) {
+            res.Error = "timed out after " + c.Timeout.String()
+        }
+    }
+    return res
+}
@@ -0,0 +1,129 @@
+// Package httperr turns errors into HTTP error responses. Handlers and
+// middleware both use it, so a request rejected by either gets the same
+// status, code and body for the same error.
+package httperr
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/francis/projectx-api/internal/apperror"
+	"github.com/francis/projectx-api/internal/model"
+	"github.com/francis/projectx-api/internal/requestid"
+	"github.com/francis/projectx-api/internal/tracing"
+	"github.com/francis/projectx-api/pkg/logger"
+	"github.com/francis/projectx-api/pkg/validator"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// Mapped is an error reduced to what a client is told about it.
+type Mapped struct {
+    Status  int
+    Code    string
+    Message string
+    Fields  validator.ValidationErrors
+    Details interface{}
+}
+
+// Map picks the status, code and client message for err. Errors with an
+// apperror kind carry their own message; anything else is internal and is
+// reported with fallback, so driver and SQL details never reach clients.
+func Map(err error, fallback string) Mapped {
+    var appErr *apperror.Error
+    if !errors.As(err, &appErr) || appErr.Kind == apperror.KindInternal {
+        return Mapped{
+            Status:  http.StatusInternalServerError,
+            Code:    apperror.KindInternal.String(),
+            Message: fallback,
+        }
+    }
+
+    mapped := Mapped{
+        Status:  appErr.HTTPStatus(),
+        Code:    appErr.ErrorCode(),
+        Message: appErr.Message,
+        Details: appErr.Details,
+    }
+    errors.As(err, &mapped.Fields)
+    return mapped
+}
+
+// Respond writes the error response for err. Internal errors are logged
+// with fallback as the message, by the request's logger so the line
+// carries the request ID; client errors are expected and are only logged
+// at debug level.
+func Respond(c *gin.Context, err error, fallback string) {
+    mapped := Map(err, fallback)
+    Log(c, err, mapped, fallback)
+    Write(c, mapped)
+}
+
+// Abort ends the request with the response for err, for middleware that
+// rejects a request before it reaches a handler.
+func Abort(c *gin.Context, err error) {
+    c.Abort()
+    Write(c, Map(err, "Internal server error"))
+}
+
+// Log records err at the level its mapped status calls for.
+func Log(c *gin.Context, err error, mapped Mapped, fallback string) {
+    log := logger.FromContext(c.Request.Context())
+    if mapped.Status >= http.StatusInternalServerError {
+        log.Error(fallback, err)
+        tracing.RecordError(c.Request.Context(), err)
+        return
+    }
+    log.Debug("Request rejected", "code", mapped.Code, "error", err.Error())
+}
+
+// Write sends mapped as RFC 7807 problem details to clients that ask for
+// application/problem+json, and in the APIResponse envelope otherwise.
+// Both carry the request ID so a client can quote it when reporting the
+// error.
+func Write(c *gin.Context, mapped Mapped) {
+    fields := LocalizeFields(c, &mapped)
+
+    if c.NegotiateFormat(binding.MIMEJSON, model.ProblemContentType) == model.ProblemContentType {
+        c.Header("Content-Type", model.ProblemContentType)
+        c.JSON(mapped.Status, model.Problem{
+            Type:      model.ProblemType(mapped.Code),
+            Title:     http.StatusText(mapped.Status),
+            Status:    mapped.Status,
+            Detail:    mapped.Message,
+            Instance:  c.Request.URL.Path,
+            Code:      mapped.Code,
+            Errors:    fields,
+            Details:   mapped.Details,
+            RequestID: requestid.Get(c),
+        })
+        return
+    }
+
+    c.JSON(mapped.Status, model.APIResponse{
+        Success:   false,
+        Error:     mapped.Message,
+        Data:      mapped.Details,
+        Code:      mapped.Code,
+        Errors:    fields,
+        RequestID: requestid.Get(c),
+    })
+}
+
+// LocalizeFields words the field errors of mapped, and the message built
+// from them, in the language asked for by Accept-Language.
+func LocalizeFields(c *gin.Context, mapped *Mapped) []model.FieldError {
+    if len(mapped.Fields) == 0 {
+        return nil
+    }
+
+    locale := validator.MatchLocale(c.GetHeader("Accept-Language"))
+    localized := mapped.Fields.Localize(locale)
+    fields := make([]model.FieldError, len(localized))
+    for i, fe := range localized {
+        fields[i] = model.FieldError{Field: fe.Field, Tag: fe.Tag, Param: fe.Param, Message: fe.Message}
+    }
+    mapped.Message = localized.Error()
+    c.Header("Content-Language", locale)
+    return fields
+}
@@ -0,0 +1,221 @@
+package httperr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/francis/projectx-api/internal/apperror"
+	"github.com/francis/projectx-api/internal/model"
+	"github.com/francis/projectx-api/internal/requestid"
+	"github.com/francis/projectx-api/pkg/validator"
+	"github.com/gin-gonic/gin"
+)
+
+func TestMapError(t *testing.T) {
+    tests := []struct {
+        name    string
+        err     error
+        status  int
+        code    string
+        message string
+    }{
+        {
+            name:    "not found",
+            err:     apperror.NotFound("User not found").WithCode("user_not_found"),
+            status:  http.StatusNotFound,
+            code:    "user_not_found",
+            message: "User not found",
+        },
+        {
+            name:    "kind as code",
+            err:     fmt.Errorf("update: %w", apperror.Conflict("username is already taken")),
+            status:  http.StatusConflict,
+            code:    "conflict",
+            message: "username is already taken",
+        },
+        {
+            name:    "rate limited",
+            err:     apperror.RateLimited("Too many requests"),
+            status:  http.StatusTooManyRequests,
+            code:    "rate_limited",
+            message: "Too many requests",
+        },
+        {
+            name:    "plain error hidden",
+            err:     errors.New(`pq: relation "users" does not exist`),
+            status:  http.StatusInternalServerError,
+            code:    "internal_error",
+            message: "Failed to load user",
+        },
+        {
+            name:    "internal kind hidden",
+            err:     apperror.Wrap(errors.New("dial tcp: connection refused"), apperror.KindInternal, ""),
+            status:  http.StatusInternalServerError,
+            code:    "internal_error",
+            message: "Failed to load user",
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            mapped := Map(tt.err, "Failed to load user")
+            if mapped.Status != tt.status {
+                t.Errorf("status = %d, want %d", mapped.Status, tt.status)
+            }
+            if mapped.Code != tt.code {
+                t.Errorf("code = %q, want %q", mapped.Code, tt.code)
+            }
+            if mapped.Message != tt.message {
+                t.Errorf("message = %q, want %q", mapped.Message, tt.message)
+            }
+        })
+    }
+}
+
+// serveError responds to a request with err, sent with the given Accept
+// header.
+func serveError(t *testing.T, err error, accept string) *httptest.ResponseRecorder {
+    t.Helper()
+
+    gin.SetMode(gin.TestMode)
+    router := gin.New()
+    router.POST("/api/v1/users", func(c *gin.Context) {
+        requestid.Set(c, "req-123")
+        Respond(c, err, "Failed to create user")
+    })
+
+    w := httptest.NewRecorder()
+    req := httptest.NewRequest(http.MethodPost, "/api/v1/users", nil)
+    if accept != "" {
+        req.Header.Set("Accept", accept)
+    }
+    router.ServeHTTP(w, req)
+    return w
+}
+
+func TestWriteErrorNegotiation(t *testing.T) {
+    err := apperror.Conflict("email is already registered").WithCode("email_taken")
+
+    tests := []struct {
+        accept      string
+        wantProblem bool
+    }{
+        {"", false},
+        {"*/*", false},
+        {"application/json", false},
+        {"application/problem+json", true},
+        {"application/problem+json, application/json", true},
+        {"application/json, application/problem+json", false},
+        {"text/html", false},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.accept, func(t *testing.T) {
+            w := serveError(t, err, tt.accept)
+            if w.Code != http.StatusConflict {
+                t.Errorf("status = %d, want %d", w.Code, http.StatusConflict)
+            }
+
+            if !tt.wantProblem {
+                if got := w.Header().Get("Content-Type"); got != "application/json; charset=utf-8" {
+                    t.Errorf("Content-Type = %q, want JSON", got)
+                }
+                var body model.APIResponse
+                if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+                    t.Fatalf("decode envelope: %v", err)
+                }
+                want := model.APIResponse{Success: false, Error: "email is already registered", Code: "email_taken", RequestID: "req-123"}
+                if !reflect.DeepEqual(body, want) {
+                    t.Errorf("body = %+v, want %+v", body, want)
+                }
+                return
+            }
+
+            if got := w.Header().Get("Content-Type"); got != model.ProblemContentType {
+                t.Errorf("Content-Type = %q, want %q", got, model.ProblemContentType)
+            }
+            var problem model.Problem
+            if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+                t.Fatalf("decode problem: %v", err)
+            }
+            want := model.Problem{
+                Type:      "urn:projectx:problem:email_taken",
+                Title:     "Conflict",
+                Status:    http.StatusConflict,
+                Detail:    "email is already registered",
+                Instance:  "/api/v1/users",
+                Code:      "email_taken",
+                RequestID: "req-123",
+            }
+            if !reflect.DeepEqual(problem, want) {
+                t.Errorf("problem = %+v, want %+v", problem, want)
+            }
+        })
+    }
+}
+
+func TestWriteErrorFieldErrors(t *testing.T) {
+    err := apperror.Invalid(validator.ValidateField("email", "not-an-email", "required,email"))
+
+    for _, accept := range []string{"application/json", model.ProblemContentType} {
+        t.Run(accept, func(t *testing.T) {
+            w := serveError(t, err, accept)
+            if w.Code != http.StatusBadRequest {
+                t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+            }
+
+            var body struct {
+                Code   string             `json:"code"`
+                Errors []model.FieldError `json:"errors"`
+            }
+            if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+                t.Fatalf("decode: %v", err)
+            }
+            if body.Code != "validation_failed" {
+                t.Errorf("code = %q, want validation_failed", body.Code)
+            }
+            if len(body.Errors) != 1 || body.Errors[0].Field != "email" || body.Errors[0].Tag != "email" || body.Errors[0].Message == "" {
+                t.Errorf("errors = %+v, want one email error with a message", body.Errors)
+            }
+        })
+    }
+}
+
+func TestWriteErrorDetails(t *testing.T) {
+    report := map[string]int{"invalid": 2}
+    err := apperror.Validation("2 of 5 rows failed validation").WithCode("import_rows_invalid").WithStatus(http.StatusUnprocessableEntity).WithDetails(report)
+
+    tests := []struct {
+        accept string
+        member string
+    }{
+        {"application/json", "data"},
+        {model.ProblemContentType, "details"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.accept, func(t *testing.T) {
+            w := serveError(t, err, tt.accept)
+            if w.Code != http.StatusUnprocessableEntity {
+                t.Errorf("status = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+            }
+
+            var body map[string]interface{}
+            if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+                t.Fatalf("decode: %v", err)
+            }
+            want := map[string]interface{}{"invalid": float64(2)}
+            if !reflect.DeepEqual(body[tt.member], want) {
+                t.Errorf("%s = %v, want %v", tt.member, body[tt.member], want)
+            }
+            if body["code"] != "import_rows_invalid" || body["request_id"] != "req-123" {
+                t.Errorf("body = %v, want the code and request ID", body)
+            }
+        })
+    }
+}
@@ -0,0 +1,37 @@
+// Package identity carries the authenticated caller through a request
+// context, so services can act on it without depending on gin.
+package identity
+
+import (
+    "context"
+)
+
+// Identity is the caller as described by their access token.
+type Identity struct {
+    UserID int
+    Email  string
+    Roles  []string
+    Org    string
+}
+
+func (id Identity) HasRole(role string) bool {
+    for _, r := range id.Roles {
+        if r == role {
+            return true
+        }
+    }
+    return false
+}
+
+type contextKey struct{}
+
+func WithIdentity(ctx context.Context, id Identity) context.Context {
+    return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the caller of the request, and false for anonymous
+// requests.
+func FromContext(ctx context.Context) (Identity, bool) {
+    id, ok := ctx.Value(contextKey{}).(Identity)
+    return id, ok
+}
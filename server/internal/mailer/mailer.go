@@ -0,0 +1,38 @@
+package mailer
+
+import (
+	"context"
+
+	"github.com/francis/projectx-api/pkg/logger"
+)
+
+type Message struct {
+    To      string
+    Subject string
+    Body    string
+}
+
+type Mailer interface {
+    Send(ctx context.Context, msg Message) error
+}
+
+// Pinger is implemented by mailers that depend on a remote server, so its
+// reachability can be checked without sending anything.
+type Pinger interface {
+    Ping(ctx context.Context) error
+}
+
+// logMailer writes messages to the log instead of delivering them. It is
+// used when no SMTP relay is configured.
+type logMailer struct {
+    logger logger.Logger
+}
+
+func NewLogMailer(logger logger.Logger) Mailer {
+    return &logMailer{logger: logger}
+}
+
+func (m *logMailer) Send(ctx context.Context, msg Message) error {
+    m.logger.Info("Email queued", "to", msg.To, "subject", msg.Subject)
+    return nil
+}
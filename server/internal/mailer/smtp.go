@@ -0,0 +1,74 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+)
+
+type SMTPConfig struct {
+    Host     string
+    Port     int
+    Username string
+    Password string
+    From     string
+}
+
+// smtpMailer delivers plain-text messages through an SMTP relay, using
+// STARTTLS when the server offers it.
+type smtpMailer struct {
+    cfg SMTPConfig
+}
+
+func NewSMTPMailer(cfg SMTPConfig) Mailer {
+    return &smtpMailer{cfg: cfg}
+}
+
+func (m *smtpMailer) Send(ctx context.Context, msg Message) error {
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+
+    var auth smtp.Auth
+    if m.cfg.Username != "" {
+        auth = smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)
+    }
+
+    var body strings.Builder
+    fmt.Fprintf(&body, "From: %s\r\n", m.cfg.From)
+    fmt.Fprintf(&body, "To: %s\r\n", msg.To)
+    fmt.Fprintf(&body, "Subject: %s\r\n", msg.Subject)
+    body.WriteString("MIME-Version: 1.0\r\n")
+    body.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+    body.WriteString(msg.Body)
+
+    addr := net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port))
+    if err := smtp.SendMail(addr, auth, m.cfg.From, []string{msg.To}, []byte(body.String())); err != nil {
+        return fmt.Errorf("send mail to %s: %w", msg.To, err)
+    }
+    return nil
+}
+
+// Ping connects to the relay and waits for its greeting.
+func (m *smtpMailer) Ping(ctx context.Context) error {
+    addr := net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port))
+    var d net.Dialer
+    conn, err := d.DialContext(ctx, "tcp", addr)
+    if err != nil {
+        return err
+    }
+    if deadline, ok := ctx.Deadline(); ok {
+        conn.SetDeadline(deadline)
+    }
+
+    client, err := smtp.NewClient(conn, m.cfg.Host)
+    if err != nil {
+        conn.Close()
+        return err
+    }
+    defer client.Close()
+    return client.Quit()
+}
@@ -0,0 +1,91 @@
+// Package metrics holds the Prometheus metrics the API exports at /metrics.
+// They live in their own registry, so only what is defined here, and the Go
+// runtime and process collectors, is exported.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "projectx"
+
+var registry = prometheus.NewRegistry()
+
+var factory = promauto.With(registry)
+
+var (
+    HTTPRequests = factory.NewCounterVec(prometheus.CounterOpts{
+        Namespace: namespace,
+        Name:      "http_requests_total",
+        Help:      "HTTP requests served, by method, route template and status.",
+    }, []string{"method", "route", "status"})
+
+    HTTPRequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+        Namespace: namespace,
+        Name:      "http_request_duration_seconds",
+        Help:      "Time taken to serve HTTP requests, by method, route template and status.",
+        Buckets:   prometheus.DefBuckets,
+    }, []string{"method", "route", "status"})
+
+    HTTPInFlight = factory.NewGauge(prometheus.GaugeOpts{
+        Namespace: namespace,
+        Name:      "http_requests_in_flight",
+        Help:      "HTTP requests being served.",
+    })
+
+    LoginAttempts = factory.NewCounterVec(prometheus.CounterOpts{
+        Namespace: namespace,
+        Name:      "auth_login_attempts_total",
+        Help:      "Login attempts, by result: success, invalid_credentials or error.",
+    }, []string{"result"})
+
+    RateLimitRejections = factory.NewCounterVec(prometheus.CounterOpts{
+        Namespace: namespace,
+        Name:      "ratelimit_rejections_total",
+        Help:      "Requests rejected by a rate limiter, by limiter.",
+    }, []string{"limiter"})
+
+    RateLimitBackendErrors = factory.NewCounter(prometheus.CounterOpts{
+        Namespace: namespace,
+        Name:      "ratelimit_backend_errors_total",
+        Help:      "Rate limit decisions made locally because Redis failed.",
+    })
+
+    PasswordHashDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+        Namespace: namespace,
+        Name:      "password_hash_duration_seconds",
+        Help:      "Time taken to hash or verify a password, by operation and algorithm.",
+        // bcrypt at the default cost takes tens of milliseconds
+        Buckets: []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+    }, []string{"operation", "algorithm"})
+)
+
+func init() {
+    registry.MustRegister(
+        collectors.NewGoCollector(),
+        collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+    )
+}
+
+// RegisterDB exports the connection pool statistics of db.
+func RegisterDB(db *sql.DB, name string) {
+    registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// ObservePasswordHash records how long a password operation that started
+// at start took.
+func ObservePasswordHash(operation, algorithm string, start time.Time) {
+    PasswordHashDuration.WithLabelValues(operation, algorithm).Observe(time.Since(start).Seconds())
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func Handler() http.Handler {
+    return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
@@ -1,10 +1,12 @@
 package middleware
 
 import (
-	"net/http"
 	"strings"
 
-	"github.com/francis/projectx-api/internal/model"
+	"github.com/francis/projectx-api/internal/apperror"
+	"github.com/francis/projectx-api/internal/httperr"
+	"github.com/francis/projectx-api/internal/identity"
+	"github.com/francis/projectx-api/pkg/logger"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -13,15 +15,13 @@ func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
     return func(c *gin.Context) {
         authHeader := c.GetHeader("Authorization")
         if authHeader == "" {
-            c.JSON(http.StatusUnauthorized, model.ErrorResponse("Authorization header required"))
-            c.Abort()
+            httperr.Abort(c, apperror.Unauthorized("Authorization header required").WithCode("missing_token"))
             return
         }
 
         bearerToken := strings.Split(authHeader, " ")
         if len(bearerToken) != 2 || bearerToken[0] != "Bearer" {
-            c.JSON(http.StatusUnauthorized, model.ErrorResponse("Invalid authorization header format"))
-            c.Abort()
+            httperr.Abort(c, apperror.Unauthorized("Invalid authorization header format").WithCode("invalid_authorization_header"))
             return
         }
 
@@ -33,27 +33,63 @@ func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
         })
 
         if err != nil || !token.Valid {
-            c.JSON(http.StatusUnauthorized, model.ErrorResponse("Invalid token"))
-            c.Abort()
+            httperr.Abort(c, apperror.Unauthorized("Invalid token").WithCode("invalid_token"))
             return
         }
 
         claims, ok := token.Claims.(jwt.MapClaims)
         if !ok {
-            c.JSON(http.StatusUnauthorized, model.ErrorResponse("Invalid token claims"))
-            c.Abort()
+            httperr.Abort(c, apperror.Unauthorized("Invalid token claims").WithCode("invalid_token"))
             return
         }
 
         userID, ok := claims["user_id"].(float64)
         if !ok {
-            c.JSON(http.StatusUnauthorized, model.ErrorResponse("Invalid user ID in token"))
-            c.Abort()
+            httperr.Abort(c, apperror.Unauthorized("Invalid user ID in token").WithCode("invalid_token"))
             return
         }
 
+        var roles []string
+        if rawRoles, ok := claims["roles"].([]interface{}); ok {
+            for _, role := range rawRoles {
+                if name, ok := role.(string); ok {
+                    roles = append(roles, name)
+                }
+            }
+        }
+
+        email, _ := claims["email"].(string)
+        org, _ := claims["org"].(string)
+
         c.Set("user_id", int(userID))
         c.Set("email", claims["email"])
+        c.Set("roles", roles)
+        ctx := identity.WithIdentity(c.Request.Context(), identity.Identity{
+            UserID: int(userID),
+            Email:  email,
+            Roles:  roles,
+            Org:    org,
+        })
+        ctx = logger.NewContext(ctx, logger.FromContext(ctx).With("user_id", int(userID)))
+        c.Request = c.Request.WithContext(ctx)
         c.Next()
     }
+}
+
+// RequireRole rejects requests whose token does not carry at least one of
+// the given roles. It must run after AuthMiddleware.
+func RequireRole(allowed ...string) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        roles := c.GetStringSlice("roles")
+        for _, role := range roles {
+            for _, want := range allowed {
+                if role == want {
+                    c.Next()
+                    return
+                }
+            }
+        }
+
+        httperr.Abort(c, apperror.Forbidden("Insufficient permissions").WithCode("insufficient_permissions"))
+    }
 }
\ No newline at end of file
@@ -1,15 +1,46 @@
 package middleware
 
 import (
+    "sync/atomic"
+
+    "github.com/francis/projectx-api/internal/config"
+    "github.com/francis/projectx-api/internal/requestid"
     "github.com/gin-contrib/cors"
     "github.com/gin-gonic/gin"
 )
 
-func CORS() gin.HandlerFunc {
-    config := cors.DefaultConfig()
-    config.AllowOrigins = []string{"*"} // Configure for production
-    config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-    config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization"}
+func CORS(cfg config.CORSConfig) gin.HandlerFunc {
+    corsConfig := cors.DefaultConfig()
+    corsConfig.AllowOrigins = cfg.AllowedOrigins
+    corsConfig.AllowMethods = cfg.AllowedMethods
+    corsConfig.AllowHeaders = cfg.AllowedHeaders
+    corsConfig.AllowCredentials = cfg.AllowCredentials
+    // Let browser clients read the request ID to quote it in bug reports
+    corsConfig.ExposeHeaders = []string{requestid.Header}
+    corsConfig.MaxAge = cfg.MaxAge.Duration
     
-    return cors.New(config)
+    return cors.New(corsConfig)
+}
+
+// CORSPolicy serves CORS headers from a config that can be replaced while
+// the server runs.
+type CORSPolicy struct {
+    handler atomic.Pointer[gin.HandlerFunc]
+}
+
+func NewCORSPolicy(cfg config.CORSConfig) *CORSPolicy {
+    p := &CORSPolicy{}
+    p.Update(cfg)
+    return p
+}
+
+func (p *CORSPolicy) Update(cfg config.CORSConfig) {
+    handler := CORS(cfg)
+    p.handler.Store(&handler)
+}
+
+func (p *CORSPolicy) Handler() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        (*p.handler.Load())(c)
+    }
 }
\ No newline at end of file
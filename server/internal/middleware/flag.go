@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"github.com/francis/projectx-api/internal/apperror"
+	"github.com/francis/projectx-api/internal/httperr"
+	"github.com/francis/projectx-api/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// RequireFlag answers 404 while the named feature flag is off for the
+// caller, so unreleased routes look like they do not exist. Put it after
+// AuthMiddleware when the flag targets users, roles or organizations.
+func RequireFlag(flags *service.FlagService, name string) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        if !flags.Enabled(c.Request.Context(), name) {
+            httperr.Abort(c, apperror.NotFound("Not found"))
+            return
+        }
+        c.Next()
+    }
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/francis/projectx-api/internal/model"
+	"github.com/francis/projectx-api/internal/repository"
+	"github.com/francis/projectx-api/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// staticFlagRepo serves a fixed set of flags.
+type staticFlagRepo struct {
+    repository.FlagRepository
+    flags []*model.FeatureFlag
+}
+
+func (r staticFlagRepo) List(ctx context.Context) ([]*model.FeatureFlag, error) {
+    return r.flags, nil
+}
+
+func TestRequireFlag(t *testing.T) {
+    repo := staticFlagRepo{flags: []*model.FeatureFlag{
+        {Name: "beta_on", Enabled: true},
+        {Name: "beta_off", Enabled: false},
+        {Name: "beta_default_on", Enabled: false},
+    }}
+    flags := service.NewFlagService(repo, map[string]bool{"config_on": true, "beta_default_on": true})
+    if err := flags.Refresh(context.Background()); err != nil {
+        t.Fatalf("Refresh() = %v", err)
+    }
+
+    tests := []struct {
+        flag string
+        want int
+    }{
+        {"beta_on", http.StatusOK},
+        {"beta_off", http.StatusNotFound},
+        {"config_on", http.StatusOK},
+        {"unknown", http.StatusNotFound},
+        // A row in the table overrides the config default
+        {"beta_default_on", http.StatusNotFound},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.flag, func(t *testing.T) {
+            gin.SetMode(gin.TestMode)
+            router := gin.New()
+            router.GET("/beta", RequireFlag(flags, tt.flag), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+            w := httptest.NewRecorder()
+            router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/beta", nil))
+            if w.Code != tt.want {
+                t.Errorf("status = %d, want %d", w.Code, tt.want)
+            }
+        })
+    }
+}
@@ -1,25 +1,109 @@
 package middleware
 
 import (
-	"fmt"
+	"net/http"
+	"sync/atomic"
 	"time"
 
+	"github.com/francis/projectx-api/internal/config"
+	"github.com/francis/projectx-api/pkg/logger"
 	"github.com/gin-gonic/gin"
 )
 
-func Logger() gin.HandlerFunc {
-    return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
-        return fmt.Sprintf("%s - [%s] \"%s %s %s %d %s \"%s\" %s\"\n",
-            param.ClientIP,
-            param.TimeStamp.Format(time.RFC1123),
-            param.Method,
-            param.Path,
-            param.Request.Proto,
-            param.StatusCode,
-            param.Latency,
-            param.Request.UserAgent(),
-            param.ErrorMessage,
-        )
-    })
+// accessLog is the access log configuration in the form the handler uses.
+type accessLog struct {
+    skip     map[string]bool
+    headers  []string
+    redactor redactor
 }
-            
\ No newline at end of file
+
+// AccessLogPolicy logs one structured line per request through the
+// request's logger, so the line carries the request ID, route and user ID.
+// Its config can be replaced while the server runs. It must run after
+// RequestID.
+type AccessLogPolicy struct {
+    current atomic.Pointer[accessLog]
+}
+
+func NewAccessLogPolicy(cfg config.AccessLogConfig) *AccessLogPolicy {
+    p := &AccessLogPolicy{}
+    p.Update(cfg)
+    return p
+}
+
+func (p *AccessLogPolicy) Update(cfg config.AccessLogConfig) {
+    next := &accessLog{
+        skip:     make(map[string]bool, len(cfg.SkipPaths)),
+        headers:  cfg.Headers,
+        redactor: newRedactor(cfg.Redact),
+    }
+    for _, path := range cfg.SkipPaths {
+        next.skip[path] = true
+    }
+    p.current.Store(next)
+}
+
+func (p *AccessLogPolicy) Handler() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        start := time.Now()
+        c.Next()
+
+        cfg := p.current.Load()
+        status := c.Writer.Status()
+        path := c.Request.URL.Path
+        if status < http.StatusInternalServerError && (cfg.skip[c.FullPath()] || cfg.skip[path]) {
+            return
+        }
+
+        args := []interface{}{
+            "method", c.Request.Method,
+            "path", cfg.redactor.text(path),
+            "status", status,
+            "duration_ms", float64(time.Since(start).Microseconds()) / 1000,
+            "bytes", max(c.Writer.Size(), 0),
+            "client_ip", c.ClientIP(),
+            "user_agent", c.Request.UserAgent(),
+        }
+        if queries, ok := c.Get(QueryCountKey); ok {
+            args = append(args, "db_queries", queries)
+        }
+        if query := c.Request.URL.RawQuery; query != "" {
+            args = append(args, "query", cfg.redactor.query(query))
+        }
+        if headers := cfg.loggedHeaders(c.Request.Header); len(headers) > 0 {
+            args = append(args, "headers", headers)
+        }
+
+        // The logger in the request context is the latest one, so it has
+        // the user ID if AuthMiddleware ran.
+        log := logger.FromContext(c.Request.Context())
+        switch {
+        case status >= http.StatusInternalServerError:
+            var err error
+            if last := c.Errors.Last(); last != nil {
+                err = last
+            }
+            log.Error("Request failed", err, args...)
+        case status >= http.StatusBadRequest:
+            log.Warn("Request rejected", args...)
+        default:
+            log.Info("Request served", args...)
+        }
+    }
+}
+
+// loggedHeaders returns the configured headers the request sent, redacted.
+func (a *accessLog) loggedHeaders(h http.Header) map[string]string {
+    var logged map[string]string
+    for _, name := range a.headers {
+        value := h.Get(name)
+        if value == "" {
+            continue
+        }
+        if logged == nil {
+            logged = make(map[string]string, len(a.headers))
+        }
+        logged[http.CanonicalHeaderKey(name)] = a.redactor.header(name, value)
+    }
+    return logged
+}
\ No newline at end of file
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/francis/projectx-api/internal/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics records the count, latency and concurrency of requests. Requests
+// are labelled with their route template rather than their path, so IDs in
+// paths cannot blow up the number of series; requests that match no route
+// share the "unmatched" label.
+func Metrics() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        start := time.Now()
+        metrics.HTTPInFlight.Inc()
+        defer metrics.HTTPInFlight.Dec()
+
+        c.Next()
+
+        route := c.FullPath()
+        if route == "" {
+            route = "unmatched"
+        }
+        status := strconv.Itoa(c.Writer.Status())
+        metrics.HTTPRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+        metrics.HTTPRequestDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+    }
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"github.com/francis/projectx-api/internal/database"
+	"github.com/francis/projectx-api/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// QueryCountKey is the gin context key holding the number of SQL
+// statements the request ran, which the access log records.
+const QueryCountKey = "db_queries"
+
+// QueryStats counts the SQL statements each request runs. A statement run
+// nPlusOne times or more in one request, usually a query issued once per
+// row of an earlier result, is logged as a likely N+1 pattern; zero turns
+// the warning off. It must run after RequestID.
+func QueryStats(nPlusOne int) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        ctx, stats := database.WithQueryStats(c.Request.Context())
+        c.Request = c.Request.WithContext(ctx)
+        c.Next()
+
+        c.Set(QueryCountKey, stats.Total())
+        if nPlusOne <= 0 {
+            return
+        }
+        log := logger.FromContext(c.Request.Context())
+        for _, q := range stats.Repeated(nPlusOne) {
+            log.Warn("Possible N+1 query", "query", q.Query, "count", q.Count, "total_queries", stats.Total())
+        }
+    }
+}
@@ -4,9 +4,15 @@ import (
 	"fmt"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/francis/projectx-api/internal/apperror"
+	"github.com/francis/projectx-api/internal/config"
+	"github.com/francis/projectx-api/internal/httperr"
+	"github.com/francis/projectx-api/internal/metrics"
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
 	"golang.org/x/time/rate"
 )
 
@@ -16,6 +22,7 @@ func TokenBucketRateLimit(rps rate.Limit, burst int) gin.HandlerFunc {
 	
 	return func(c *gin.Context) {
 		if !limiter.Allow() {
+			metrics.RateLimitRejections.WithLabelValues("token_bucket").Inc()
 			c.Header("X-RateLimit-Limit", fmt.Sprintf("%.0f", float64(rps)))
 			c.Header("X-RateLimit-Remaining", "0")
 			c.Header("Retry-After", "1")
@@ -31,31 +38,44 @@ func TokenBucketRateLimit(rps rate.Limit, burst int) gin.HandlerFunc {
 
 // Method 2: Per-IP Rate Limiter with cleanup
 type IPRateLimiter struct {
-	ips map[string]*rate.Limiter
-	mu  *sync.RWMutex
-	r   rate.Limit
-	b   int
+	ips  map[string]*rate.Limiter
+	mu   *sync.RWMutex
+	r    rate.Limit
+	b    int
+	done chan struct{}
 }
 
 func NewIPRateLimiter(r rate.Limit, b int) *IPRateLimiter {
 	i := &IPRateLimiter{
-		ips: make(map[string]*rate.Limiter),
-		mu:  &sync.RWMutex{},
-		r:   r,
-		b:   b,
+		ips:  make(map[string]*rate.Limiter),
+		mu:   &sync.RWMutex{},
+		r:    r,
+		b:    b,
+		done: make(chan struct{}),
 	}
 
 	// Clean up old entries every minute
 	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
 		for {
-			time.Sleep(time.Minute)
-			i.CleanupStaleEntries()
+			select {
+			case <-ticker.C:
+				i.CleanupStaleEntries()
+			case <-i.done:
+				return
+			}
 		}
 	}()
 
 	return i
 }
 
+// Stop ends the cleanup goroutine of a limiter that is no longer used.
+func (i *IPRateLimiter) Stop() {
+	close(i.done)
+}
+
 func (i *IPRateLimiter) AddIP(ip string) *rate.Limiter {
 	i.mu.Lock()
 	defer i.mu.Unlock()
@@ -88,20 +108,24 @@ func (i *IPRateLimiter) CleanupStaleEntries() {
 	}
 }
 
+// Len returns the number of clients being tracked.
+func (i *IPRateLimiter) Len() int {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return len(i.ips)
+}
+
 func IPRateLimit(rateLimiter *IPRateLimiter) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ip := c.ClientIP()
 		limiter := rateLimiter.GetLimiter(ip)
 
 		if !limiter.Allow() {
+			metrics.RateLimitRejections.WithLabelValues("ip").Inc()
 			c.Header("X-RateLimit-Limit", fmt.Sprintf("%.0f", float64(rateLimiter.r)))
 			c.Header("X-RateLimit-Remaining", "0")
 			c.Header("Retry-After", "1")
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error":   "Rate limit exceeded",
-				"message": "Too many requests from your IP address",
-			})
-			c.Abort()
+			httperr.Abort(c, apperror.RateLimited("Too many requests from your IP address"))
 			return
 		}
 
@@ -109,6 +133,82 @@ func IPRateLimit(rateLimiter *IPRateLimiter) gin.HandlerFunc {
 	}
 }
 
+// RateLimitPolicy applies the per-IP limit from the rate limit config and
+// lets it be replaced while the server runs. With the redis backend the
+// limit is shared between replicas. A disabled policy lets every request
+// through.
+type RateLimitPolicy struct {
+	client *redis.Client
+	state  atomic.Pointer[rateLimitState]
+}
+
+// rateLimitState is the limiter in use. The local limiter is always set
+// when enabled; redis is set for the redis backend and falls back to it.
+type rateLimitState struct {
+	local *IPRateLimiter
+	redis *RedisRateLimiter
+}
+
+// NewRateLimitPolicy creates the policy for cfg. client is used by the
+// redis backend and may be nil, in which case limits are kept in memory.
+func NewRateLimitPolicy(cfg config.RateLimitConfig, client *redis.Client) *RateLimitPolicy {
+	p := &RateLimitPolicy{client: client}
+	p.Update(cfg)
+	return p
+}
+
+// Update swaps in a limiter for cfg. Clients start again with a full burst
+// of the local limiter; counts kept in Redis carry over.
+func (p *RateLimitPolicy) Update(cfg config.RateLimitConfig) {
+	next := &rateLimitState{}
+	if cfg.Enabled {
+		perSecond := rate.Limit(float64(cfg.Requests) / cfg.Window.Seconds())
+		next.local = NewIPRateLimiter(perSecond, cfg.Requests)
+		if cfg.Backend == config.RateLimitBackendRedis && p.client != nil {
+			next.redis = NewRedisRateLimiter(p.client, cfg.Algorithm, cfg.Requests, cfg.Window.Duration, next.local)
+		}
+	}
+	if old := p.state.Swap(next); old != nil && old.local != nil {
+		old.local.Stop()
+	}
+}
+
+// RateLimitStats describes the state of a RateLimitPolicy. Clients counts
+// the IPs held by the local limiter.
+type RateLimitStats struct {
+	Enabled   bool   `json:"enabled"`
+	Backend   string `json:"backend,omitempty"`
+	Clients   int    `json:"clients"`
+	RedisDown bool   `json:"redis_down,omitempty"`
+}
+
+func (p *RateLimitPolicy) Stats() RateLimitStats {
+	state := p.state.Load()
+	if state.local == nil {
+		return RateLimitStats{}
+	}
+	stats := RateLimitStats{Enabled: true, Backend: config.RateLimitBackendMemory, Clients: state.local.Len()}
+	if state.redis != nil {
+		stats.Backend = config.RateLimitBackendRedis
+		stats.RedisDown = state.redis.Down()
+	}
+	return stats
+}
+
+func (p *RateLimitPolicy) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		state := p.state.Load()
+		switch {
+		case state.redis != nil:
+			RedisRateLimit(state.redis)(c)
+		case state.local != nil:
+			IPRateLimit(state.local)(c)
+		default:
+			c.Next()
+		}
+	}
+}
+
 // Method 3: Simple Time Window Rate Limiter
 type WindowRateLimiter struct {
 	requests map[string][]time.Time
@@ -189,6 +289,7 @@ func WindowRateLimit(limiter *WindowRateLimiter) gin.HandlerFunc {
 		ip := c.ClientIP()
 
 		if !limiter.Allow(ip) {
+			metrics.RateLimitRejections.WithLabelValues("window").Inc()
 			c.Header("X-RateLimit-Limit", fmt.Sprintf("%d", limiter.limit))
 			c.Header("X-RateLimit-Window", limiter.window.String())
 			c.JSON(http.StatusTooManyRequests, gin.H{
@@ -245,67 +346,4 @@ func WindowRateLimit(limiter *WindowRateLimiter) gin.HandlerFunc {
 // 	})
 
 // 	r.Run(":8080")
-// }
-
-// Advanced: Rate limiter with Redis backend
-/*
-import (
-	"github.com/go-redis/redis/v8"
-	"context"
-	"strconv"
-)
-
-type RedisRateLimiter struct {
-	client *redis.Client
-	limit  int
-	window time.Duration
-}
-
-func NewRedisRateLimiter(client *redis.Client, limit int, window time.Duration) *RedisRateLimiter {
-	return &RedisRateLimiter{
-		client: client,
-		limit:  limit,
-		window: window,
-	}
-}
-
-func (rrl *RedisRateLimiter) Allow(ctx context.Context, key string) (bool, error) {
-	pipe := rrl.client.Pipeline()
-	
-	incr := pipe.Incr(ctx, key)
-	pipe.Expire(ctx, key, rrl.window)
-	
-	_, err := pipe.Exec(ctx)
-	if err != nil {
-		return false, err
-	}
-	
-	count, err := incr.Result()
-	if err != nil {
-		return false, err
-	}
-	
-	return count <= int64(rrl.limit), nil
-}
-
-func RedisRateLimit(limiter *RedisRateLimiter) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		key := fmt.Sprintf("rate_limit:%s", c.ClientIP())
-		
-		allowed, err := limiter.Allow(c.Request.Context(), key)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
-			c.Abort()
-			return
-		}
-		
-		if !allowed {
-			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Rate limit exceeded"})
-			c.Abort()
-			return
-		}
-		
-		c.Next()
-	}
-}
-*/
\ No newline at end of file
+// }
\ No newline at end of file
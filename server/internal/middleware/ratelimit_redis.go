@@ -0,0 +1,188 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/francis/projectx-api/internal/apperror"
+	"github.com/francis/projectx-api/internal/config"
+	"github.com/francis/projectx-api/internal/httperr"
+	"github.com/francis/projectx-api/internal/metrics"
+	"github.com/francis/projectx-api/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// gcraScript implements the generic cell rate algorithm. The key holds the
+// theoretical arrival time (TAT) of the next request in microseconds; a
+// request is allowed when it arrives no more than burst intervals before
+// it. Times come from the Redis clock so replicas agree on them, which
+// needs Redis 5 or later for scripts that write after calling TIME.
+//
+// Returns {allowed, remaining, retry after in microseconds}.
+var gcraScript = redis.NewScript(`
+local interval = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+
+local tat = tonumber(redis.call('GET', KEYS[1]))
+if not tat or tat < now then
+	tat = now
+end
+
+local allow_at = tat + interval - burst * interval
+if allow_at > now then
+	return {0, 0, allow_at - now}
+end
+
+local new_tat = tat + interval
+redis.call('SET', KEYS[1], new_tat, 'PX', math.ceil((new_tat - now) / 1000))
+return {1, math.floor((now - allow_at) / interval), 0}
+`)
+
+// slidingWindowScript keeps the arrival time of each request allowed in the
+// last window in a sorted set, so no window of that length ever holds more
+// than limit requests.
+//
+// Returns {allowed, remaining, retry after in microseconds}.
+var slidingWindowScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+local count = redis.call('ZCARD', KEYS[1])
+if count < limit then
+	redis.call('ZADD', KEYS[1], now, ARGV[3])
+	redis.call('PEXPIRE', KEYS[1], math.ceil(window / 1000))
+	return {1, limit - count - 1, 0}
+end
+
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+return {0, 0, tonumber(oldest[2]) + window - now}
+`)
+
+// redisRetryInterval is how long the local limiter decides alone after
+// Redis fails, so an outage does not add a timeout to every request.
+const redisRetryInterval = 5 * time.Second
+
+var errRedisUnavailable = errors.New("redis rate limiter unavailable")
+
+// RateLimitResult is the decision for one request.
+type RateLimitResult struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+// RedisRateLimiter enforces one limit across every replica by keeping the
+// counts in Redis. Each decision is a single Lua script, so concurrent
+// requests cannot both take the last slot. While Redis fails, requests are
+// limited by the local limiter instead.
+type RedisRateLimiter struct {
+	client    redis.Scripter
+	algorithm string
+	limit     int
+	window    time.Duration
+	local     *IPRateLimiter
+	downUntil atomic.Int64
+}
+
+// NewRedisRateLimiter allows limit requests per window for each key, using
+// algorithm (config.RateLimitGCRA or config.RateLimitSlidingWindow). local
+// takes over while Redis cannot be reached.
+func NewRedisRateLimiter(client redis.Scripter, algorithm string, limit int, window time.Duration, local *IPRateLimiter) *RedisRateLimiter {
+	return &RedisRateLimiter{
+		client:    client,
+		algorithm: algorithm,
+		limit:     limit,
+		window:    window,
+		local:     local,
+	}
+}
+
+// Allow records a request for key and reports whether it is within the
+// limit. It fails fast with errRedisUnavailable for a while after Redis
+// has failed.
+func (l *RedisRateLimiter) Allow(ctx context.Context, key string) (RateLimitResult, error) {
+	if time.Now().UnixNano() < l.downUntil.Load() {
+		return RateLimitResult{}, errRedisUnavailable
+	}
+
+	res, err := l.run(ctx, key)
+	if err != nil {
+		if l.downUntil.Swap(time.Now().Add(redisRetryInterval).UnixNano()) == 0 {
+			logger.FromContext(ctx).Warn("Redis rate limiter failed, limiting locally",
+				"error", err.Error(), "retry_in", redisRetryInterval.String())
+		}
+		return RateLimitResult{}, err
+	}
+	if l.downUntil.Swap(0) != 0 {
+		logger.FromContext(ctx).Info("Redis rate limiter recovered")
+	}
+	return res, nil
+}
+
+// Down reports whether the local limiter is standing in for Redis.
+func (l *RedisRateLimiter) Down() bool {
+	return time.Now().UnixNano() < l.downUntil.Load()
+}
+
+func (l *RedisRateLimiter) run(ctx context.Context, key string) (RateLimitResult, error) {
+	redisKey := fmt.Sprintf("ratelimit:%s:%s", l.algorithm, key)
+
+	var cmd *redis.Cmd
+	switch l.algorithm {
+	case config.RateLimitSlidingWindow:
+		member := strconv.FormatInt(time.Now().UnixNano(), 36) + "-" + strconv.FormatInt(rand.Int63(), 36)
+		cmd = slidingWindowScript.Run(ctx, l.client, []string{redisKey}, l.limit, l.window.Microseconds(), member)
+	default:
+		interval := max(l.window.Microseconds()/int64(l.limit), 1)
+		cmd = gcraScript.Run(ctx, l.client, []string{redisKey}, interval, l.limit)
+	}
+
+	values, err := cmd.Int64Slice()
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	if len(values) != 3 {
+		return RateLimitResult{}, fmt.Errorf("rate limit script returned %d values", len(values))
+	}
+	return RateLimitResult{
+		Allowed:    values[0] == 1,
+		Remaining:  int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Microsecond,
+	}, nil
+}
+
+// RedisRateLimit limits each client IP with limiter, falling back to its
+// local limiter when Redis fails.
+func RedisRateLimit(limiter *RedisRateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		res, err := limiter.Allow(c.Request.Context(), c.ClientIP())
+		if err != nil {
+			metrics.RateLimitBackendErrors.Inc()
+			IPRateLimit(limiter.local)(c)
+			return
+		}
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(limiter.limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		if !res.Allowed {
+			metrics.RateLimitRejections.WithLabelValues("redis").Inc()
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(res.RetryAfter.Seconds()))))
+			httperr.Abort(c, apperror.RateLimited("Too many requests from your IP address"))
+			return
+		}
+
+		c.Next()
+	}
+}
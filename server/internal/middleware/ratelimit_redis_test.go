@@ -0,0 +1,155 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/francis/projectx-api/internal/config"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/time/rate"
+)
+
+func newTestRedisLimiter(t *testing.T, algorithm string, limit int, window time.Duration) (*RedisRateLimiter, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	mr.SetTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { client.Close() })
+
+	local := NewIPRateLimiter(rate.Limit(1), 1)
+	t.Cleanup(local.Stop)
+	return NewRedisRateLimiter(client, algorithm, limit, window, local), mr
+}
+
+func newTestRateLimitRouter(limiter *RedisRateLimiter) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RedisRateLimit(limiter))
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+func doRateLimitedRequest(router *gin.Engine) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRedisRateLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		algorithm  string
+		limit      int
+		window     time.Duration
+		retryAfter string
+	}{
+		// One request every 5s, so the next slot opens 5s after the burst
+		{"gcra", config.RateLimitGCRA, 2, 10 * time.Second, "5"},
+		// The oldest request leaves the window 10s after it was made
+		{"sliding window", config.RateLimitSlidingWindow, 2, 10 * time.Second, "10"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter, _ := newTestRedisLimiter(t, tt.algorithm, tt.limit, tt.window)
+			router := newTestRateLimitRouter(limiter)
+
+			for i := 0; i < tt.limit; i++ {
+				w := doRateLimitedRequest(router)
+				if w.Code != http.StatusOK {
+					t.Fatalf("request %d: status = %d, want %d", i+1, w.Code, http.StatusOK)
+				}
+				if got, want := w.Header().Get("X-RateLimit-Remaining"), strconv.Itoa(tt.limit-i-1); got != want {
+					t.Errorf("request %d: X-RateLimit-Remaining = %q, want %q", i+1, got, want)
+				}
+			}
+
+			w := doRateLimitedRequest(router)
+			if w.Code != http.StatusTooManyRequests {
+				t.Fatalf("request over the limit: status = %d, want %d", w.Code, http.StatusTooManyRequests)
+			}
+			if got := w.Header().Get("Retry-After"); got != tt.retryAfter {
+				t.Errorf("Retry-After = %q, want %q", got, tt.retryAfter)
+			}
+			if got := w.Header().Get("X-RateLimit-Remaining"); got != "0" {
+				t.Errorf("X-RateLimit-Remaining = %q, want %q", got, "0")
+			}
+		})
+	}
+}
+
+func TestRedisRateLimiterSlidingWindowExpiry(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter, mr := newTestRedisLimiter(t, config.RateLimitSlidingWindow, 2, 10*time.Second)
+	ctx := context.Background()
+
+	steps := []struct {
+		at         time.Duration
+		allowed    bool
+		retryAfter time.Duration
+	}{
+		{0, true, 0},
+		{4 * time.Second, true, 0},
+		// Both requests are still in the window
+		{9 * time.Second, false, time.Second},
+		// The first request has left the window, the second has not
+		{10*time.Second + time.Millisecond, true, 0},
+		{12 * time.Second, false, 2 * time.Second},
+		// Both have left it
+		{14*time.Second + time.Millisecond, true, 0},
+	}
+
+	for _, step := range steps {
+		mr.SetTime(start.Add(step.at))
+		res, err := limiter.Allow(ctx, "client")
+		if err != nil {
+			t.Fatalf("at %s: %v", step.at, err)
+		}
+		if res.Allowed != step.allowed {
+			t.Errorf("at %s: allowed = %v, want %v", step.at, res.Allowed, step.allowed)
+		}
+		if res.RetryAfter != step.retryAfter {
+			t.Errorf("at %s: retry after = %s, want %s", step.at, res.RetryAfter, step.retryAfter)
+		}
+	}
+}
+
+func TestRedisRateLimitFallsBackToLocal(t *testing.T) {
+	limiter, mr := newTestRedisLimiter(t, config.RateLimitGCRA, 100, time.Second)
+	router := newTestRateLimitRouter(limiter)
+
+	if w := doRateLimitedRequest(router); w.Code != http.StatusOK {
+		t.Fatalf("with redis: status = %d, want %d", w.Code, http.StatusOK)
+	}
+	mr.Close()
+
+	// The local limiter allows a burst of one, far below the redis limit
+	if w := doRateLimitedRequest(router); w.Code != http.StatusOK {
+		t.Fatalf("first request without redis: status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if !limiter.Down() {
+		t.Error("Down() = false after redis failed, want true")
+	}
+	w := doRateLimitedRequest(router)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("second request without redis: status = %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+	if got := w.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After = %q, want %q", got, "1")
+	}
+
+	// Redis is not tried again until the retry interval has passed
+	if _, err := limiter.Allow(context.Background(), "client"); !errors.Is(err, errRedisUnavailable) {
+		t.Errorf("Allow() error = %v, want %v", err, errRedisUnavailable)
+	}
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/francis/projectx-api/internal/requestid"
+	"github.com/francis/projectx-api/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// maxRequestIDLength bounds IDs accepted from clients and proxies.
+const maxRequestIDLength = 128
+
+// RequestID keeps the X-Request-ID sent by a client or proxy, or makes one
+// up, and echoes it in the response. It stores the ID for requestid.Get
+// and puts a logger with the request ID and route into the request
+// context, for logger.FromContext. It should run first so every other
+// middleware can log with it.
+func RequestID(log logger.Logger) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        id := c.GetHeader(requestid.Header)
+        if !validRequestID(id) {
+            id = newRequestID()
+        }
+        requestid.Set(c, id)
+        c.Header(requestid.Header, id)
+
+        reqLog := log.With("request_id", id)
+        if route := c.FullPath(); route != "" {
+            reqLog = reqLog.With("route", route)
+        }
+        c.Request = c.Request.WithContext(logger.NewContext(c.Request.Context(), reqLog))
+        c.Next()
+    }
+}
+
+// validRequestID accepts IDs made of characters that are safe to echo in a
+// header and write to logs.
+func validRequestID(id string) bool {
+    if id == "" || len(id) > maxRequestIDLength {
+        return false
+    }
+    for _, r := range id {
+        switch {
+        case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+        case r == '-', r == '_', r == '.', r == ':':
+        default:
+            return false
+        }
+    }
+    return true
+}
+
+func newRequestID() string {
+    b := make([]byte, 16)
+    rand.Read(b)
+    return hex.EncodeToString(b)
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/francis/projectx-api/internal/requestid"
+	"github.com/francis/projectx-api/internal/tracing"
+	"github.com/francis/projectx-api/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// Tracing starts a server span for each request, continuing the trace of
+// an incoming traceparent header and sending the span's own traceparent
+// back in the response. The request's logger gets the trace and span IDs,
+// so log lines can be found from a trace and the other way round. It must
+// run after RequestID.
+func Tracing() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        propagator := otel.GetTextMapPropagator()
+        ctx := propagator.Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+        route := c.FullPath()
+        name := c.Request.Method
+        if route != "" {
+            name += " " + route
+        }
+        ctx, span := tracing.StartServer(ctx, name,
+            semconv.HTTPRequestMethodKey.String(c.Request.Method),
+            semconv.HTTPRoute(route),
+            semconv.URLPath(c.Request.URL.Path),
+            semconv.ClientAddress(c.ClientIP()),
+            attribute.String("request_id", requestid.Get(c)),
+        )
+        defer span.End()
+
+        if sc := span.SpanContext(); sc.IsValid() {
+            log := logger.FromContext(ctx).With("trace_id", sc.TraceID().String()).With("span_id", sc.SpanID().String())
+            ctx = logger.NewContext(ctx, log)
+        }
+        propagator.Inject(ctx, propagation.HeaderCarrier(c.Writer.Header()))
+        c.Request = c.Request.WithContext(ctx)
+
+        c.Next()
+
+        status := c.Writer.Status()
+        span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+        if last := c.Errors.Last(); last != nil {
+            span.RecordError(last)
+        }
+        if status >= http.StatusInternalServerError {
+            span.SetStatus(codes.Error, http.StatusText(status))
+        }
+    }
+}
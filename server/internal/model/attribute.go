@@ -0,0 +1,30 @@
+package model
+
+import (
+    "time"
+)
+
+const (
+    AttributeTypeString  = "string"
+    AttributeTypeNumber  = "number"
+    AttributeTypeBoolean = "boolean"
+    AttributeTypeDate    = "date"
+    AttributeTypeEnum    = "enum"
+)
+
+// Attributes holds custom attribute values keyed by AttributeDefinition.Key.
+type Attributes map[string]interface{}
+
+// AttributeDefinition describes a custom user attribute defined by an
+// admin. Values are stored in User.Attributes under Key.
+type AttributeDefinition struct {
+    ID         int       `json:"id" db:"id"`
+    Key        string    `json:"key" db:"key" validate:"required,max=64,attrkey"`
+    Label      string    `json:"label" db:"label" validate:"required,max=255"`
+    Type       string    `json:"type" db:"type" validate:"required,oneof=string number boolean date enum"`
+    Required   bool      `json:"required" db:"required"`
+    EnumValues []string  `json:"enum_values" db:"enum_values" validate:"required_if=Type enum"`
+    Pattern    string    `json:"pattern,omitempty" db:"pattern" validate:"omitempty,regexp"`
+    CreatedAt  time.Time `json:"created_at" db:"created_at"`
+    UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
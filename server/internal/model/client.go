@@ -0,0 +1,54 @@
+package model
+
+import (
+    "strconv"
+    "strings"
+)
+
+// The types below mirror the contract expected by the bundled React client
+// (client/src/app/contexts/auth/Provider.tsx and @types/user.ts). They are
+// returned unwrapped, without the APIResponse envelope.
+
+type ClientLoginRequest struct {
+    Username string `json:"username" validate:"required"`
+    Password string `json:"password" validate:"required"`
+}
+
+type ClientLoginResponse struct {
+    AuthToken string     `json:"authToken"`
+    User      ClientUser `json:"user"`
+}
+
+type ClientProfileResponse struct {
+    User ClientUser `json:"user"`
+}
+
+type ClientUser struct {
+    ID        string `json:"id"`
+    Name      string `json:"name"`
+    Username  string `json:"username,omitempty"`
+    Email     string `json:"email,omitempty"`
+    Role      string `json:"role,omitempty"`
+    AvatarURL string `json:"avatarUrl,omitempty"`
+}
+
+// ClientErrorResponse carries a message the client can show as-is.
+type ClientErrorResponse struct {
+    Message   string `json:"message"`
+    RequestID string `json:"requestId,omitempty"`
+}
+
+// NewClientUser converts a user to the client's shape. The first role is
+// reported, as the client only understands a single role.
+func NewClientUser(user *User, roles []string) ClientUser {
+    clientUser := ClientUser{
+        ID:       strconv.Itoa(user.ID),
+        Name:     strings.TrimSpace(user.FirstName + " " + user.LastName),
+        Username: user.Username,
+        Email:    user.Email,
+    }
+    if len(roles) > 0 {
+        clientUser.Role = roles[0]
+    }
+    return clientUser
+}
@@ -0,0 +1,40 @@
+package model
+
+import (
+    "time"
+)
+
+// OrgAttribute is the custom attribute holding a user's organization. It is
+// copied into access tokens so feature flags can target organizations, so
+// only the admin import sets it; users cannot set or change it themselves.
+const OrgAttribute = "org"
+
+// FlagUserImport gates the admin bulk user import.
+const FlagUserImport = "user_import"
+
+// FeatureFlag switches a feature on for some or all users. A disabled flag
+// is off for everyone. An enabled flag without rules is on for everyone;
+// with rules it is on for users matching at least one rule.
+type FeatureFlag struct {
+    ID          int        `json:"id" db:"id"`
+    Name        string     `json:"name" db:"name" validate:"required,flagname"`
+    Description string     `json:"description" db:"description" validate:"max=255"`
+    Enabled     bool       `json:"enabled" db:"enabled"`
+    Rules       []FlagRule `json:"rules" db:"rules" validate:"dive"`
+    CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+    UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// FlagRule selects users by ID, role, organization or a stable percentage
+// of user IDs. Every condition set on a rule must match.
+type FlagRule struct {
+    UserIDs    []int    `json:"user_ids,omitempty"`
+    Roles      []string `json:"roles,omitempty"`
+    Orgs       []string `json:"orgs,omitempty"`
+    Percentage *int     `json:"percentage,omitempty" validate:"omitempty,min=0,max=100"`
+}
+
+// IsEmpty reports whether the rule sets no conditions.
+func (r FlagRule) IsEmpty() bool {
+    return len(r.UserIDs) == 0 && len(r.Roles) == 0 && len(r.Orgs) == 0 && r.Percentage == nil
+}
@@ -0,0 +1,55 @@
+package model
+
+import (
+    "time"
+)
+
+type ImportFormat string
+
+const (
+    ImportFormatCSV  ImportFormat = "csv"
+    ImportFormatJSON ImportFormat = "json"
+)
+
+// ImportUserRow is a single user in an import file. Users migrated from
+// another system may supply PasswordHash with the PasswordAlgo it was
+// produced by instead of a plaintext Password; Firebase exports also need
+// the per-user PasswordSalt.
+type ImportUserRow struct {
+    Email        string     `json:"email" validate:"required,email"`
+    Username     string     `json:"username,omitempty" validate:"omitempty,username"`
+    FirstName    string     `json:"first_name" validate:"required"`
+    LastName     string     `json:"last_name" validate:"required"`
+    Password     string     `json:"password,omitempty" validate:"omitempty,min=8"`
+    PasswordHash string     `json:"password_hash,omitempty" validate:"required_with=PasswordAlgo"`
+    PasswordAlgo string     `json:"password_algo,omitempty" validate:"required_with=PasswordHash,omitempty,oneof=bcrypt firebase_scrypt django_pbkdf2 sha512_crypt"`
+    PasswordSalt string     `json:"password_salt,omitempty"`
+    Attributes   Attributes `json:"attributes,omitempty"`
+}
+
+type ImportOptions struct {
+    Format      ImportFormat
+    DryRun      bool
+    SkipInvalid bool
+    SendInvites bool
+    BatchSize   int
+}
+
+// ImportReport is the result of validating an import file without writing
+// anything to the database.
+type ImportReport struct {
+    Total   int        `json:"total"`
+    Valid   int        `json:"valid"`
+    Invalid int        `json:"invalid"`
+    Errors  []RowError `json:"errors"`
+}
+
+// UserFilter narrows user listings and exports. Zero values are ignored.
+// Attributes must hold typed values, as returned by
+// AttributeService.ParseFilter, and match by equality.
+type UserFilter struct {
+    Email         string
+    CreatedAfter  *time.Time
+    CreatedBefore *time.Time
+    Attributes    Attributes
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+    "time"
+)
+
+type JobStatus string
+
+const (
+    JobStatusPending   JobStatus = "pending"
+    JobStatusRunning   JobStatus = "running"
+    JobStatusCompleted JobStatus = "completed"
+    JobStatusFailed    JobStatus = "failed"
+)
+
+const (
+    JobTypeUserImport = "user_import"
+)
+
+type Job struct {
+    ID         string     `json:"id" db:"id"`
+    Type       string     `json:"type" db:"type"`
+    Status     JobStatus  `json:"status" db:"status"`
+    Total      int        `json:"total" db:"total"`
+    Processed  int        `json:"processed" db:"processed"`
+    Succeeded  int        `json:"succeeded" db:"succeeded"`
+    Failed     int        `json:"failed" db:"failed"`
+    Errors     []RowError `json:"errors" db:"errors"`
+    CreatedBy  int        `json:"created_by" db:"created_by"`
+    CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+    UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+    FinishedAt *time.Time `json:"finished_at,omitempty" db:"finished_at"`
+}
+
+// RowError describes why a single row of a bulk operation was rejected.
+// Row numbers are 1-based and exclude the CSV header.
+type RowError struct {
+    Row   int    `json:"row"`
+    Email string `json:"email,omitempty"`
+    Error string `json:"error"`
+}
@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// LogLevelRequest changes the log level for TTL, optionally only for the
+// loggers of one module or the requests of one user. TTL is a Go duration
+// such as "15m".
+type LogLevelRequest struct {
+    Level  string `json:"level" validate:"required,oneof=debug info warn error"`
+    Module string `json:"module"`
+    UserID int    `json:"user_id" validate:"gte=0"`
+    TTL    string `json:"ttl"`
+}
+
+// LogLevelState is the configured log level and the overrides in effect.
+type LogLevelState struct {
+    Level     string             `json:"level"`
+    Overrides []LogLevelOverride `json:"overrides"`
+}
+
+type LogLevelOverride struct {
+    Level     string    `json:"level"`
+    Module    string    `json:"module,omitempty"`
+    UserID    int       `json:"user_id,omitempty"`
+    ExpiresAt time.Time `json:"expires_at"`
+}
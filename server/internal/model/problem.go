@@ -0,0 +1,29 @@
+package model
+
+// ProblemContentType is the media type of Problem responses.
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object. Clients that send
+// Accept: application/problem+json get errors in this form instead of the
+// APIResponse envelope. Code is stable across releases; Detail is meant for
+// people and may change. Details is an extension member with data about
+// the failure, such as the per-row report of a rejected import. RequestID
+// matches the X-Request-ID response header, for quoting in support
+// requests.
+type Problem struct {
+    Type      string       `json:"type"`
+    Title     string       `json:"title"`
+    Status    int          `json:"status"`
+    Detail    string       `json:"detail,omitempty"`
+    Instance  string       `json:"instance,omitempty"`
+    Code      string       `json:"code"`
+    Errors    []FieldError `json:"errors,omitempty"`
+    Details   interface{}  `json:"details,omitempty"`
+    RequestID string       `json:"request_id,omitempty"`
+}
+
+// ProblemType returns the type URI for a problem code. The URIs identify
+// the problem only; they are not meant to be dereferenced.
+func ProblemType(code string) string {
+    return "urn:projectx:problem:" + code
+}
@@ -1,10 +1,22 @@
 package model
 
 type APIResponse struct {
-    Success bool        `json:"success"`
-    Message string      `json:"message,omitempty"`
-    Data    interface{} `json:"data,omitempty"`
-    Error   string      `json:"error,omitempty"`
+    Success   bool         `json:"success"`
+    Message   string       `json:"message,omitempty"`
+    Data      interface{}  `json:"data,omitempty"`
+    Error     string       `json:"error,omitempty"`
+    Code      string       `json:"code,omitempty"`
+    Errors    []FieldError `json:"errors,omitempty"`
+    RequestID string       `json:"request_id,omitempty"`
+}
+
+// FieldError reports one invalid field of a request body: the rule it
+// broke, the rule's parameter and a message in the caller's language.
+type FieldError struct {
+    Field   string `json:"field"`
+    Tag     string `json:"tag"`
+    Param   string `json:"param,omitempty"`
+    Message string `json:"message"`
 }
 
 type PaginatedResponse struct {
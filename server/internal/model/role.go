@@ -0,0 +1,13 @@
+package model
+
+const (
+    RoleAdmin     = "admin"
+    RoleUser      = "user"
+    RoleModerator = "moderator"
+)
+
+type Role struct {
+    ID          int    `json:"id" db:"id"`
+    Name        string `json:"name" db:"name"`
+    Description string `json:"description" db:"description"`
+}
@@ -5,29 +5,51 @@ import (
 )
 
 type User struct {
-    ID        int       `json:"id" db:"id"`
-    Email     string    `json:"email" db:"email" validate:"required,email"`
-    FirstName string    `json:"first_name" db:"first_name" validate:"required"`
-    LastName  string    `json:"last_name" db:"last_name" validate:"required"`
-    Password  string    `json:"-" db:"password_hash"`
-    CreatedAt time.Time `json:"created_at" db:"created_at"`
-    UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+    ID           int        `json:"id" db:"id"`
+    Email        string     `json:"email" db:"email" validate:"required,email"`
+    Username     string     `json:"username,omitempty" db:"username" validate:"omitempty,username"`
+    FirstName    string     `json:"first_name" db:"first_name" validate:"required"`
+    LastName     string     `json:"last_name" db:"last_name" validate:"required"`
+    Password     string     `json:"-" db:"password_hash"`
+    PasswordAlgo string     `json:"-" db:"password_algo"` // empty means bcrypt
+    Attributes   Attributes `json:"attributes,omitempty" db:"attributes"`
+    CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+    UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
 }
 
 type CreateUserRequest struct {
-    Email     string `json:"email" validate:"required,email"`
-    FirstName string `json:"first_name" validate:"required"`
-    LastName  string `json:"last_name" validate:"required"`
-    Password  string `json:"password" validate:"required,min=8"`
+    Email      string     `json:"email" validate:"required,email"`
+    Username   string     `json:"username,omitempty" validate:"omitempty,username"`
+    FirstName  string     `json:"first_name" validate:"required"`
+    LastName   string     `json:"last_name" validate:"required"`
+    Password   string     `json:"password" validate:"required,min=8"`
+    Attributes Attributes `json:"attributes,omitempty"`
 }
 
+// LoginRequest identifies the user by email or username. A username
+// containing "@" is treated as an email, since login forms often use a
+// single field for both.
 type LoginRequest struct {
-    Email    string `json:"email" validate:"required,email"`
+    Email    string `json:"email" validate:"required_without=Username,omitempty,email"`
+    Username string `json:"username" validate:"required_without=Email"`
     Password string `json:"password" validate:"required"`
 }
 
 type LoginResponse struct {
-    Token        string `json:"token"`
-    RefreshToken string `json:"refresh_token"`
-    User         User   `json:"user"`
+    Token        string   `json:"token"`
+    RefreshToken string   `json:"refresh_token"`
+    User         User     `json:"user"`
+    Roles        []string `json:"roles,omitempty"`
+}
+// UserSearchResult is a user matched by a search query. Highlight fields are
+// HTML-escaped with matching terms wrapped in <mark> tags.
+type UserSearchResult struct {
+    User
+    Rank      float64       `json:"rank"`
+    Highlight UserHighlight `json:"highlight"`
+}
+
+type UserHighlight struct {
+    Name  string `json:"name"`
+    Email string `json:"email"`
 }
\ No newline at end of file
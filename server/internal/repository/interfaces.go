@@ -2,16 +2,64 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/francis/projectx-api/internal/model"
 )
 
+// TxManager runs a unit of work in one transaction. Repositories called
+// with the context passed to fn take part in the transaction without any
+// change to their signatures.
+type TxManager interface {
+    WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
 type UserRepository interface {
     Create(ctx context.Context, user *model.User) error
+    CreateBatch(ctx context.Context, users []*model.User) error
     GetByID(ctx context.Context, id int) (*model.User, error)
     GetByEmail(ctx context.Context, email string) (*model.User, error)
+    GetByUsername(ctx context.Context, username string) (*model.User, error)
+    ExistingEmails(ctx context.Context, emails []string) (map[string]bool, error)
+    ExistingUsernames(ctx context.Context, usernames []string) (map[string]bool, error)
     Update(ctx context.Context, user *model.User) error
+    UpdatePassword(ctx context.Context, id int, hash, algo string) error
     Delete(ctx context.Context, id int) error
-    List(ctx context.Context, limit, offset int) ([]*model.User, error)
-    Count(ctx context.Context) (int64, error)
+    List(ctx context.Context, filter model.UserFilter, limit, offset int) ([]*model.User, error)
+    Stream(ctx context.Context, filter model.UserFilter, fn func(*model.User) error) error
+    Count(ctx context.Context, filter model.UserFilter) (int64, error)
+    // Search ranks users by how well their name and email match query,
+    // tolerating typos. It returns the page of results and the total
+    // number of matches.
+    Search(ctx context.Context, query string, limit, offset int) ([]*model.UserSearchResult, int64, error)
+}
+
+type RoleRepository interface {
+    GetUserRoles(ctx context.Context, userID int) ([]string, error)
+    AssignRole(ctx context.Context, userID int, role string) error
+}
+
+type AttributeRepository interface {
+    List(ctx context.Context) ([]*model.AttributeDefinition, error)
+    GetByKey(ctx context.Context, key string) (*model.AttributeDefinition, error)
+    Create(ctx context.Context, def *model.AttributeDefinition) error
+    Update(ctx context.Context, def *model.AttributeDefinition) error
+    Delete(ctx context.Context, key string) error
+}
+
+type FlagRepository interface {
+    List(ctx context.Context) ([]*model.FeatureFlag, error)
+    GetByName(ctx context.Context, name string) (*model.FeatureFlag, error)
+    Create(ctx context.Context, flag *model.FeatureFlag) error
+    Update(ctx context.Context, flag *model.FeatureFlag) error
+    Delete(ctx context.Context, name string) error
+}
+
+type JobRepository interface {
+    Create(ctx context.Context, job *model.Job) error
+    GetByID(ctx context.Context, id string) (*model.Job, error)
+    Update(ctx context.Context, job *model.Job) error
+    // FailStale marks pending and running jobs not updated since before as
+    // failed, recording reason, and returns how many it marked.
+    FailStale(ctx context.Context, before time.Time, reason string) (int64, error)
 }
\ No newline at end of file
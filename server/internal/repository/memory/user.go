@@ -0,0 +1,407 @@
+// Package memory provides in-memory repositories for exercising services
+// without a database.
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/francis/projectx-api/internal/apperror"
+	"github.com/francis/projectx-api/internal/model"
+	"github.com/francis/projectx-api/internal/repository"
+	"github.com/francis/projectx-api/pkg/utils"
+)
+
+// searchThreshold matches pg_trgm's default similarity_threshold.
+const searchThreshold = 0.3
+
+type userRepository struct {
+    mu     sync.RWMutex
+    users  map[int]*model.User
+    nextID int
+}
+
+func NewUserRepository() repository.UserRepository {
+    return &userRepository{
+        users:  make(map[int]*model.User),
+        nextID: 1,
+    }
+}
+
+func (r *userRepository) Create(ctx context.Context, user *model.User) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    return r.insert(user, time.Now())
+}
+
+func (r *userRepository) CreateBatch(ctx context.Context, users []*model.User) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    // Check the whole batch first so a failure leaves nothing behind.
+    seen := make(map[string]bool, len(users))
+    for _, user := range users {
+        if err := r.checkUnique(user); err != nil {
+            return err
+        }
+        username := "username:" + strings.ToLower(user.Username)
+        if seen[user.Email] || (user.Username != "" && seen[username]) {
+            return apperror.Conflict("insert %s: duplicate email or username", user.Email).WithCode("email_taken")
+        }
+        seen[user.Email] = true
+        seen[username] = true
+    }
+
+    now := time.Now()
+    for _, user := range users {
+        if err := r.insert(user, now); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func (r *userRepository) checkUnique(user *model.User) error {
+    if r.findByEmail(user.Email) != nil {
+        return apperror.Conflict("email is already registered").WithCode("email_taken")
+    }
+    if user.Username != "" && r.findByUsername(user.Username) != nil {
+        return apperror.Conflict("username is already taken").WithCode("username_taken")
+    }
+    return nil
+}
+
+func (r *userRepository) insert(user *model.User, now time.Time) error {
+    if err := r.checkUnique(user); err != nil {
+        return err
+    }
+
+    user.ID = r.nextID
+    r.nextID++
+    user.CreatedAt = now
+    user.UpdatedAt = now
+    if user.PasswordAlgo == "" {
+        user.PasswordAlgo = utils.HashBcrypt
+    }
+
+    stored := *user
+    r.users[user.ID] = &stored
+    return nil
+}
+
+func (r *userRepository) findByEmail(email string) *model.User {
+    for _, user := range r.users {
+        if strings.EqualFold(user.Email, email) {
+            return user
+        }
+    }
+    return nil
+}
+
+func (r *userRepository) findByUsername(username string) *model.User {
+    for _, user := range r.users {
+        if user.Username != "" && strings.EqualFold(user.Username, username) {
+            return user
+        }
+    }
+    return nil
+}
+
+func (r *userRepository) GetByID(ctx context.Context, id int) (*model.User, error) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    user, ok := r.users[id]
+    if !ok {
+        return nil, apperror.NotFound("User not found").WithCode("user_not_found")
+    }
+    found := *user
+    return &found, nil
+}
+
+func (r *userRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    user := r.findByEmail(email)
+    if user == nil {
+        return nil, apperror.NotFound("User not found").WithCode("user_not_found")
+    }
+    found := *user
+    return &found, nil
+}
+
+func (r *userRepository) GetByUsername(ctx context.Context, username string) (*model.User, error) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    user := r.findByUsername(username)
+    if user == nil {
+        return nil, apperror.NotFound("User not found").WithCode("user_not_found")
+    }
+    found := *user
+    return &found, nil
+}
+
+func (r *userRepository) ExistingEmails(ctx context.Context, emails []string) (map[string]bool, error) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    existing := make(map[string]bool)
+    for _, email := range emails {
+        if r.findByEmail(email) != nil {
+            existing[email] = true
+        }
+    }
+    return existing, nil
+}
+
+func (r *userRepository) ExistingUsernames(ctx context.Context, usernames []string) (map[string]bool, error) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    existing := make(map[string]bool)
+    for _, username := range usernames {
+        if r.findByUsername(username) != nil {
+            existing[username] = true
+        }
+    }
+    return existing, nil
+}
+
+func (r *userRepository) Update(ctx context.Context, user *model.User) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    stored, ok := r.users[user.ID]
+    if !ok {
+        return apperror.NotFound("User not found").WithCode("user_not_found")
+    }
+
+    user.UpdatedAt = time.Now()
+    stored.Email = user.Email
+    stored.FirstName = user.FirstName
+    stored.LastName = user.LastName
+    stored.UpdatedAt = user.UpdatedAt
+    if user.Attributes != nil {
+        stored.Attributes = user.Attributes
+    }
+    if user.Username != "" {
+        stored.Username = user.Username
+    }
+    return nil
+}
+
+func (r *userRepository) UpdatePassword(ctx context.Context, id int, hash, algo string) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    stored, ok := r.users[id]
+    if !ok {
+        return apperror.NotFound("User not found").WithCode("user_not_found")
+    }
+    stored.Password = hash
+    stored.PasswordAlgo = algo
+    stored.UpdatedAt = time.Now()
+    return nil
+}
+
+func (r *userRepository) Delete(ctx context.Context, id int) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    if _, ok := r.users[id]; !ok {
+        return apperror.NotFound("User not found").WithCode("user_not_found")
+    }
+    delete(r.users, id)
+    return nil
+}
+
+// sorted returns copies of the users matching keep, oldest first.
+func (r *userRepository) sorted(keep func(*model.User) bool) []*model.User {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    var users []*model.User
+    for _, user := range r.users {
+        if keep == nil || keep(user) {
+            found := *user
+            users = append(users, &found)
+        }
+    }
+    sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+    return users
+}
+
+func (r *userRepository) List(ctx context.Context, filter model.UserFilter, limit, offset int) ([]*model.User, error) {
+    users := r.sorted(matchFilter(filter))
+
+    // Newest first, like the postgres implementation.
+    sort.SliceStable(users, func(i, j int) bool { return users[i].CreatedAt.After(users[j].CreatedAt) })
+    return paginate(users, limit, offset), nil
+}
+
+func (r *userRepository) Stream(ctx context.Context, filter model.UserFilter, fn func(*model.User) error) error {
+    users := r.sorted(matchFilter(filter))
+
+    for _, user := range users {
+        if err := ctx.Err(); err != nil {
+            return err
+        }
+        if err := fn(user); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func (r *userRepository) Count(ctx context.Context, filter model.UserFilter) (int64, error) {
+    return int64(len(r.sorted(matchFilter(filter)))), nil
+}
+
+func matchFilter(filter model.UserFilter) func(*model.User) bool {
+    return func(user *model.User) bool {
+        if filter.Email != "" && !strings.Contains(strings.ToLower(user.Email), strings.ToLower(filter.Email)) {
+            return false
+        }
+        if filter.CreatedAfter != nil && user.CreatedAt.Before(*filter.CreatedAfter) {
+            return false
+        }
+        if filter.CreatedBefore != nil && !user.CreatedAt.Before(*filter.CreatedBefore) {
+            return false
+        }
+        for key, want := range filter.Attributes {
+            if got, ok := user.Attributes[key]; !ok || got != want {
+                return false
+            }
+        }
+        return true
+    }
+}
+
+// Search approximates the postgres implementation: every term must prefix a
+// word of the name or email, or the whole query must be trigram-similar to
+// the user's name and email.
+func (r *userRepository) Search(ctx context.Context, query string, limit, offset int) ([]*model.UserSearchResult, int64, error) {
+    terms := repository.SearchTerms(query)
+    if len(terms) == 0 {
+        return []*model.UserSearchResult{}, 0, nil
+    }
+    raw := strings.Join(terms, " ")
+
+    var results []*model.UserSearchResult
+    for _, user := range r.sorted(nil) {
+        name := user.FirstName + " " + user.LastName
+        words := repository.SearchTerms(name + " " + user.Email)
+
+        matched := 0
+        for _, term := range terms {
+            for _, word := range words {
+                if strings.HasPrefix(word, term) {
+                    matched++
+                    break
+                }
+            }
+        }
+
+        similarity := trigramSimilarity(raw, name+" "+user.Email)
+        if matched < len(terms) && similarity < searchThreshold {
+            continue
+        }
+
+        rank := similarity
+        if matched == len(terms) {
+            rank += 1
+        }
+        results = append(results, &model.UserSearchResult{
+            User: *user,
+            Rank: rank,
+            Highlight: model.UserHighlight{
+                Name:  repository.RenderHighlight(highlight(repository.StripHighlight(name), terms)),
+                Email: repository.RenderHighlight(highlight(repository.StripHighlight(user.Email), terms)),
+            },
+        })
+    }
+
+    sort.SliceStable(results, func(i, j int) bool { return results[i].Rank > results[j].Rank })
+    return paginate(results, limit, offset), int64(len(results)), nil
+}
+
+func paginate[T any](items []T, limit, offset int) []T {
+    if offset >= len(items) {
+        return []T{}
+    }
+    items = items[offset:]
+    if limit > 0 && limit < len(items) {
+        items = items[:limit]
+    }
+    return items
+}
+
+// highlight wraps every word of s that starts with one of terms in
+// highlight markers.
+func highlight(s string, terms []string) string {
+    var out strings.Builder
+    word := func(w string) {
+        lower := strings.ToLower(w)
+        for _, term := range terms {
+            if strings.HasPrefix(lower, term) {
+                out.WriteString(repository.HighlightStart + w + repository.HighlightStop)
+                return
+            }
+        }
+        out.WriteString(w)
+    }
+
+    start := -1
+    for i, r := range s {
+        isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+        switch {
+        case isWord && start < 0:
+            start = i
+        case !isWord && start >= 0:
+            word(s[start:i])
+            start = -1
+        }
+        if !isWord {
+            out.WriteRune(r)
+        }
+    }
+    if start >= 0 {
+        word(s[start:])
+    }
+    return out.String()
+}
+
+// trigramSimilarity mirrors pg_trgm's similarity(): the share of distinct
+// three-letter sequences the two strings have in common.
+func trigramSimilarity(a, b string) float64 {
+    ta, tb := trigrams(a), trigrams(b)
+    if len(ta) == 0 || len(tb) == 0 {
+        return 0
+    }
+
+    shared := 0
+    for t := range ta {
+        if tb[t] {
+            shared++
+        }
+    }
+    return float64(shared) / float64(len(ta)+len(tb)-shared)
+}
+
+func trigrams(s string) map[string]bool {
+    set := make(map[string]bool)
+    for _, word := range repository.SearchTerms(s) {
+        padded := []rune("  " + word + " ")
+        for i := 0; i+3 <= len(padded); i++ {
+            set[string(padded[i:i+3])] = true
+        }
+    }
+    return set
+}
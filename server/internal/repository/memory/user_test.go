@@ -0,0 +1,163 @@
+package memory
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/francis/projectx-api/internal/model"
+)
+
+func newSearchRepository(t *testing.T) *userRepository {
+    t.Helper()
+
+    repo := NewUserRepository().(*userRepository)
+    users := []*model.User{
+        {Email: "jonathan.smith@example.com", FirstName: "Jonathan", LastName: "Smith"},
+        {Email: "jane.doe@example.com", FirstName: "Jane", LastName: "Doe"},
+        {Email: "smithers@example.org", FirstName: "Waylon", LastName: "Smithers"},
+        {Email: "mallory@example.net", FirstName: "\x03</mark>Mal\x02", LastName: "Lory"},
+    }
+    for _, user := range users {
+        if err := repo.Create(context.Background(), user); err != nil {
+            t.Fatalf("Create(%s): %v", user.Email, err)
+        }
+    }
+    return repo
+}
+
+func TestUserRepositorySearch(t *testing.T) {
+    repo := newSearchRepository(t)
+
+    tests := []struct {
+        name      string
+        query     string
+        wantEmail []string
+        wantName  []string
+    }{
+        {
+            name:      "empty query",
+            query:     " ?! ",
+            wantEmail: []string{},
+        },
+        {
+            name:      "prefixes of every term",
+            query:     "jon smi",
+            wantEmail: []string{"jonathan.smith@example.com"},
+            wantName:  []string{"<mark>Jonathan</mark> <mark>Smith</mark>"},
+        },
+        {
+            name:      "closest match first",
+            query:     "smith",
+            wantEmail: []string{"jonathan.smith@example.com", "smithers@example.org"},
+            wantName:  []string{"Jonathan <mark>Smith</mark>", "Waylon <mark>Smithers</mark>"},
+        },
+        {
+            name:      "misspelling found by trigram similarity",
+            query:     "jonathon smith",
+            wantEmail: []string{"jonathan.smith@example.com"},
+            wantName:  []string{"Jonathan <mark>Smith</mark>"},
+        },
+        {
+            name:      "no match",
+            query:     "zzz",
+            wantEmail: []string{},
+        },
+        {
+            name:      "markers saved in a name are not rendered",
+            query:     "mal",
+            wantEmail: []string{"mallory@example.net"},
+            wantName:  []string{"&lt;/mark&gt;<mark>Mal</mark> Lory"},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            results, total, err := repo.Search(context.Background(), tt.query, 10, 0)
+            if err != nil {
+                t.Fatalf("Search(%q): %v", tt.query, err)
+            }
+
+            emails := []string{}
+            var names []string
+            for _, result := range results {
+                emails = append(emails, result.Email)
+                names = append(names, result.Highlight.Name)
+            }
+            if !reflect.DeepEqual(emails, tt.wantEmail) {
+                t.Errorf("Search(%q) emails = %q, want %q", tt.query, emails, tt.wantEmail)
+            }
+            if tt.wantName != nil && !reflect.DeepEqual(names, tt.wantName) {
+                t.Errorf("Search(%q) highlighted names = %q, want %q", tt.query, names, tt.wantName)
+            }
+            if total != int64(len(tt.wantEmail)) {
+                t.Errorf("Search(%q) total = %d, want %d", tt.query, total, len(tt.wantEmail))
+            }
+        })
+    }
+}
+
+func TestUserRepositorySearchPaginates(t *testing.T) {
+    repo := newSearchRepository(t)
+
+    tests := []struct {
+        limit, offset int
+        want          []string
+    }{
+        {1, 0, []string{"jonathan.smith@example.com"}},
+        {1, 1, []string{"smithers@example.org"}},
+        {10, 2, []string{}},
+    }
+
+    for _, tt := range tests {
+        results, total, err := repo.Search(context.Background(), "smith", tt.limit, tt.offset)
+        if err != nil {
+            t.Fatalf("Search: %v", err)
+        }
+        emails := []string{}
+        for _, result := range results {
+            emails = append(emails, result.Email)
+        }
+        if !reflect.DeepEqual(emails, tt.want) {
+            t.Errorf("limit %d offset %d: emails = %q, want %q", tt.limit, tt.offset, emails, tt.want)
+        }
+        if total != 2 {
+            t.Errorf("limit %d offset %d: total = %d, want 2", tt.limit, tt.offset, total)
+        }
+    }
+}
+
+func TestTrigramSimilarity(t *testing.T) {
+    tests := []struct {
+        a, b string
+        want float64
+    }{
+        {"", "jon", 0},
+        {"jon", "jon", 1},
+        {"JON", "jon", 1},
+        // "  j", " jo", "jon", "on " against "  j", " ja", "jan", "an "
+        {"jon", "jan", 1.0 / 7},
+    }
+
+    for _, tt := range tests {
+        if got := trigramSimilarity(tt.a, tt.b); got != tt.want {
+            t.Errorf("trigramSimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+        }
+    }
+}
+
+func TestUserRepositoryExistingEmails(t *testing.T) {
+    repo := NewUserRepository()
+    if err := repo.Create(context.Background(), &model.User{Email: "Jane.Doe@Example.com"}); err != nil {
+        t.Fatalf("Create: %v", err)
+    }
+
+    got, err := repo.ExistingEmails(context.Background(), []string{"jane.doe@example.com", "john@example.com"})
+    if err != nil {
+        t.Fatalf("ExistingEmails: %v", err)
+    }
+    want := map[string]bool{"jane.doe@example.com": true}
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("ExistingEmails() = %v, want %v", got, want)
+    }
+}
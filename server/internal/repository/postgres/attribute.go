@@ -0,0 +1,115 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/francis/projectx-api/internal/model"
+	"github.com/francis/projectx-api/internal/repository"
+)
+
+type attributeRepository struct {
+    db *sql.DB
+}
+
+func NewAttributeRepository(db *sql.DB) repository.AttributeRepository {
+    return &attributeRepository{db: db}
+}
+
+const attributeColumns = `id, key, label, type, required, enum_values, pattern, created_at, updated_at`
+
+func scanAttribute(row interface{ Scan(...interface{}) error }) (*model.AttributeDefinition, error) {
+    def := &model.AttributeDefinition{}
+    var enumValues []byte
+
+    err := row.Scan(&def.ID, &def.Key, &def.Label, &def.Type, &def.Required,
+        &enumValues, &def.Pattern, &def.CreatedAt, &def.UpdatedAt)
+    if err != nil {
+        return nil, err
+    }
+    if err := json.Unmarshal(enumValues, &def.EnumValues); err != nil {
+        return nil, err
+    }
+    return def, nil
+}
+
+func (r *attributeRepository) List(ctx context.Context) ([]*model.AttributeDefinition, error) {
+    query := `SELECT ` + attributeColumns + ` FROM attribute_definitions ORDER BY key`
+
+    rows, err := conn(ctx, r.db).QueryContext(ctx, query)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    defs := []*model.AttributeDefinition{}
+    for rows.Next() {
+        def, err := scanAttribute(rows)
+        if err != nil {
+            return nil, err
+        }
+        defs = append(defs, def)
+    }
+    return defs, rows.Err()
+}
+
+func (r *attributeRepository) GetByKey(ctx context.Context, key string) (*model.AttributeDefinition, error) {
+    query := `SELECT ` + attributeColumns + ` FROM attribute_definitions WHERE key = $1`
+    def, err := scanAttribute(conn(ctx, r.db).QueryRowContext(ctx, query, key))
+    return def, translate(err, "Attribute")
+}
+
+func (r *attributeRepository) Create(ctx context.Context, def *model.AttributeDefinition) error {
+    enumValues, err := json.Marshal(enumOrEmpty(def.EnumValues))
+    if err != nil {
+        return err
+    }
+
+    query := `
+        INSERT INTO attribute_definitions (key, label, type, required, enum_values, pattern, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        RETURNING id`
+
+    now := time.Now()
+    def.CreatedAt = now
+    def.UpdatedAt = now
+
+    err = conn(ctx, r.db).QueryRowContext(ctx, query,
+        def.Key, def.Label, def.Type, def.Required, enumValues, def.Pattern,
+        def.CreatedAt, def.UpdatedAt).Scan(&def.ID)
+    return translate(err, "Attribute")
+}
+
+func (r *attributeRepository) Update(ctx context.Context, def *model.AttributeDefinition) error {
+    enumValues, err := json.Marshal(enumOrEmpty(def.EnumValues))
+    if err != nil {
+        return err
+    }
+
+    query := `
+        UPDATE attribute_definitions
+        SET label = $2, type = $3, required = $4, enum_values = $5, pattern = $6, updated_at = $7
+        WHERE key = $1
+        RETURNING id, created_at`
+
+    def.UpdatedAt = time.Now()
+    err = conn(ctx, r.db).QueryRowContext(ctx, query,
+        def.Key, def.Label, def.Type, def.Required, enumValues, def.Pattern,
+        def.UpdatedAt).Scan(&def.ID, &def.CreatedAt)
+    return translate(err, "Attribute")
+}
+
+func (r *attributeRepository) Delete(ctx context.Context, key string) error {
+    query := `DELETE FROM attribute_definitions WHERE key = $1`
+    result, err := conn(ctx, r.db).ExecContext(ctx, query, key)
+    return notFoundUnlessAffected(result, err, "Attribute")
+}
+
+func enumOrEmpty(values []string) []string {
+    if values == nil {
+        return []string{}
+    }
+    return values
+}
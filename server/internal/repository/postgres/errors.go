@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/francis/projectx-api/internal/apperror"
+	"github.com/lib/pq"
+)
+
+// uniqueConflicts gives the code and message for each unique constraint,
+// so a conflict tells the client which value is taken.
+var uniqueConflicts = map[string]struct{ code, message string }{
+    "users_email_key":               {"email_taken", "email is already registered"},
+    "idx_users_email_lower":         {"email_taken", "email is already registered"},
+    "idx_users_username_lower":      {"username_taken", "username is already taken"},
+    "attribute_definitions_key_key": {"attribute_exists", "attribute already exists"},
+    "feature_flags_name_key":        {"flag_exists", "flag already exists"},
+}
+
+// translate turns driver errors the caller can act on into apperror kinds.
+// resource names the row type in not-found messages, such as "User".
+// Serialization failures and deadlocks are left alone so WithinTx can
+// retry them.
+func translate(err error, resource string) error {
+    if err == nil {
+        return nil
+    }
+    if errors.Is(err, sql.ErrNoRows) {
+        return notFound(err, resource)
+    }
+
+    var pqErr *pq.Error
+    if !errors.As(err, &pqErr) {
+        return err
+    }
+    switch pqErr.Code.Name() {
+    case "unique_violation":
+        if conflict, ok := uniqueConflicts[pqErr.Constraint]; ok {
+            return apperror.Wrap(err, apperror.KindConflict, "%s", conflict.message).WithCode(conflict.code)
+        }
+        return apperror.Wrap(err, apperror.KindConflict, "%s already exists", resource).WithCode(resourceCode(resource, "exists"))
+    case "foreign_key_violation":
+        return apperror.Wrap(err, apperror.KindConflict, "%s refers to a missing record or is still in use", resource)
+    case "check_violation", "not_null_violation", "string_data_right_truncation", "invalid_text_representation":
+        return apperror.Wrap(err, apperror.KindValidation, "invalid %s", resource)
+    }
+    return err
+}
+
+// notFoundUnlessAffected reports a NotFound error when an UPDATE or DELETE
+// matched no row.
+func notFoundUnlessAffected(result sql.Result, err error, resource string) error {
+    if err != nil {
+        return translate(err, resource)
+    }
+    if n, err := result.RowsAffected(); err == nil && n == 0 {
+        return notFound(sql.ErrNoRows, resource)
+    }
+    return nil
+}
+
+func notFound(err error, resource string) error {
+    return apperror.Wrap(err, apperror.KindNotFound, "%s not found", resource).WithCode(resourceCode(resource, "not_found"))
+}
+
+// resourceCode builds an error code such as "user_not_found".
+func resourceCode(resource, suffix string) string {
+    return strings.ToLower(resource) + "_" + suffix
+}
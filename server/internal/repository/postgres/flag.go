@@ -0,0 +1,115 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/francis/projectx-api/internal/model"
+	"github.com/francis/projectx-api/internal/repository"
+)
+
+type flagRepository struct {
+    db *sql.DB
+}
+
+func NewFlagRepository(db *sql.DB) repository.FlagRepository {
+    return &flagRepository{db: db}
+}
+
+const flagColumns = `id, name, description, enabled, rules, created_at, updated_at`
+
+func scanFlag(row interface{ Scan(...interface{}) error }) (*model.FeatureFlag, error) {
+    flag := &model.FeatureFlag{}
+    var rules []byte
+
+    err := row.Scan(&flag.ID, &flag.Name, &flag.Description, &flag.Enabled,
+        &rules, &flag.CreatedAt, &flag.UpdatedAt)
+    if err != nil {
+        return nil, err
+    }
+    if err := json.Unmarshal(rules, &flag.Rules); err != nil {
+        return nil, err
+    }
+    return flag, nil
+}
+
+func (r *flagRepository) List(ctx context.Context) ([]*model.FeatureFlag, error) {
+    query := `SELECT ` + flagColumns + ` FROM feature_flags ORDER BY name`
+
+    rows, err := conn(ctx, r.db).QueryContext(ctx, query)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    flags := []*model.FeatureFlag{}
+    for rows.Next() {
+        flag, err := scanFlag(rows)
+        if err != nil {
+            return nil, err
+        }
+        flags = append(flags, flag)
+    }
+    return flags, rows.Err()
+}
+
+func (r *flagRepository) GetByName(ctx context.Context, name string) (*model.FeatureFlag, error) {
+    query := `SELECT ` + flagColumns + ` FROM feature_flags WHERE name = $1`
+    flag, err := scanFlag(conn(ctx, r.db).QueryRowContext(ctx, query, name))
+    return flag, translate(err, "Flag")
+}
+
+func (r *flagRepository) Create(ctx context.Context, flag *model.FeatureFlag) error {
+    rules, err := json.Marshal(rulesOrEmpty(flag.Rules))
+    if err != nil {
+        return err
+    }
+
+    query := `
+        INSERT INTO feature_flags (name, description, enabled, rules, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id`
+
+    now := time.Now()
+    flag.CreatedAt = now
+    flag.UpdatedAt = now
+
+    err = conn(ctx, r.db).QueryRowContext(ctx, query,
+        flag.Name, flag.Description, flag.Enabled, rules,
+        flag.CreatedAt, flag.UpdatedAt).Scan(&flag.ID)
+    return translate(err, "Flag")
+}
+
+func (r *flagRepository) Update(ctx context.Context, flag *model.FeatureFlag) error {
+    rules, err := json.Marshal(rulesOrEmpty(flag.Rules))
+    if err != nil {
+        return err
+    }
+
+    query := `
+        UPDATE feature_flags
+        SET description = $2, enabled = $3, rules = $4, updated_at = $5
+        WHERE name = $1
+        RETURNING id, created_at`
+
+    flag.UpdatedAt = time.Now()
+    err = conn(ctx, r.db).QueryRowContext(ctx, query,
+        flag.Name, flag.Description, flag.Enabled, rules,
+        flag.UpdatedAt).Scan(&flag.ID, &flag.CreatedAt)
+    return translate(err, "Flag")
+}
+
+func (r *flagRepository) Delete(ctx context.Context, name string) error {
+    query := `DELETE FROM feature_flags WHERE name = $1`
+    result, err := conn(ctx, r.db).ExecContext(ctx, query, name)
+    return notFoundUnlessAffected(result, err, "Flag")
+}
+
+func rulesOrEmpty(rules []model.FlagRule) []model.FlagRule {
+    if rules == nil {
+        return []model.FlagRule{}
+    }
+    return rules
+}
@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/francis/projectx-api/internal/model"
+	"github.com/francis/projectx-api/internal/repository"
+	"github.com/lib/pq"
+)
+
+type jobRepository struct {
+    db *sql.DB
+}
+
+func NewJobRepository(db *sql.DB) repository.JobRepository {
+    return &jobRepository{db: db}
+}
+
+func (r *jobRepository) Create(ctx context.Context, job *model.Job) error {
+    query := `
+        INSERT INTO jobs (type, status, total, created_by, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id`
+
+    now := time.Now()
+    job.CreatedAt = now
+    job.UpdatedAt = now
+    if job.Errors == nil {
+        job.Errors = []model.RowError{}
+    }
+
+    return conn(ctx, r.db).QueryRowContext(ctx, query,
+        job.Type, job.Status, job.Total, job.CreatedBy,
+        job.CreatedAt, job.UpdatedAt).Scan(&job.ID)
+}
+
+func (r *jobRepository) GetByID(ctx context.Context, id string) (*model.Job, error) {
+    job := &model.Job{}
+    var errorsJSON []byte
+    var createdBy sql.NullInt64
+    var finishedAt sql.NullTime
+
+    query := `
+        SELECT id, type, status, total, processed, succeeded, failed, errors,
+               created_by, created_at, updated_at, finished_at
+        FROM jobs WHERE id = $1`
+
+    err := conn(ctx, r.db).QueryRowContext(ctx, query, id).Scan(
+        &job.ID, &job.Type, &job.Status, &job.Total, &job.Processed,
+        &job.Succeeded, &job.Failed, &errorsJSON, &createdBy,
+        &job.CreatedAt, &job.UpdatedAt, &finishedAt)
+    if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "invalid_text_representation" {
+        // Job IDs are UUIDs; anything else cannot name a job
+        err = sql.ErrNoRows
+    }
+    if err != nil {
+        return nil, translate(err, "Job")
+    }
+
+    if err := json.Unmarshal(errorsJSON, &job.Errors); err != nil {
+        return nil, err
+    }
+    job.CreatedBy = int(createdBy.Int64)
+    if finishedAt.Valid {
+        job.FinishedAt = &finishedAt.Time
+    }
+    return job, nil
+}
+
+func (r *jobRepository) Update(ctx context.Context, job *model.Job) error {
+    errorsJSON, err := json.Marshal(job.Errors)
+    if err != nil {
+        return err
+    }
+
+    query := `
+        UPDATE jobs
+        SET status = $2, total = $3, processed = $4, succeeded = $5, failed = $6,
+            errors = $7, finished_at = $8, updated_at = $9
+        WHERE id = $1`
+
+    job.UpdatedAt = time.Now()
+    _, err = conn(ctx, r.db).ExecContext(ctx, query,
+        job.ID, job.Status, job.Total, job.Processed, job.Succeeded, job.Failed,
+        errorsJSON, job.FinishedAt, job.UpdatedAt)
+    return err
+}
+
+func (r *jobRepository) FailStale(ctx context.Context, before time.Time, reason string) (int64, error) {
+    errorsJSON, err := json.Marshal([]model.RowError{{Error: reason}})
+    if err != nil {
+        return 0, err
+    }
+
+    query := `
+        UPDATE jobs
+        SET status = $1, errors = errors || $2::jsonb, finished_at = $3, updated_at = $3
+        WHERE status IN ($4, $5) AND updated_at < $6`
+
+    result, err := conn(ctx, r.db).ExecContext(ctx, query,
+        model.JobStatusFailed, errorsJSON, time.Now(),
+        model.JobStatusPending, model.JobStatusRunning, before)
+    if err != nil {
+        return 0, err
+    }
+    return result.RowsAffected()
+}
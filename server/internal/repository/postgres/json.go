@@ -0,0 +1,36 @@
+package postgres
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
+	"github.com/francis/projectx-api/internal/model"
+)
+
+// jsonAttributes stores model.Attributes in a JSONB column. A nil map is
+// written as an empty object.
+type jsonAttributes model.Attributes
+
+func (a jsonAttributes) Value() (driver.Value, error) {
+    if a == nil {
+        return []byte("{}"), nil
+    }
+    return json.Marshal(a)
+}
+
+func (a *jsonAttributes) Scan(src interface{}) error {
+    var data []byte
+    switch v := src.(type) {
+    case nil:
+        *a = nil
+        return nil
+    case []byte:
+        data = v
+    case string:
+        data = []byte(v)
+    default:
+        return fmt.Errorf("cannot scan %T into attributes", src)
+    }
+    return json.Unmarshal(data, a)
+}
@@ -0,0 +1,26 @@
+package postgres
+
+import (
+	"database/sql"
+)
+
+// nullString scans a nullable text column into a plain string, mapping
+// NULL to "".
+type nullString string
+
+func (s *nullString) Scan(src interface{}) error {
+    var ns sql.NullString
+    if err := ns.Scan(src); err != nil {
+        return err
+    }
+    *s = nullString(ns.String)
+    return nil
+}
+
+// nullIfEmpty stores "" as NULL, so optional unique columns don't collide.
+func nullIfEmpty(s string) interface{} {
+    if s == "" {
+        return nil
+    }
+    return s
+}
@@ -0,0 +1,62 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/francis/projectx-api/internal/repository"
+)
+
+type roleRepository struct {
+    db *sql.DB
+}
+
+func NewRoleRepository(db *sql.DB) repository.RoleRepository {
+    return &roleRepository{db: db}
+}
+
+func (r *roleRepository) GetUserRoles(ctx context.Context, userID int) ([]string, error) {
+    query := `
+        SELECT r.name
+        FROM roles r
+        JOIN user_roles ur ON ur.role_id = r.id
+        WHERE ur.user_id = $1
+        ORDER BY r.name`
+
+    rows, err := conn(ctx, r.db).QueryContext(ctx, query, userID)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var roles []string
+    for rows.Next() {
+        var name string
+        if err := rows.Scan(&name); err != nil {
+            return nil, err
+        }
+        roles = append(roles, name)
+    }
+    return roles, rows.Err()
+}
+
+// AssignRole gives the user the named role. Assigning a role the user
+// already has is not an error.
+func (r *roleRepository) AssignRole(ctx context.Context, userID int, role string) error {
+    var roleID int
+    err := conn(ctx, r.db).QueryRowContext(ctx, `SELECT id FROM roles WHERE name = $1`, role).Scan(&roleID)
+    if err == sql.ErrNoRows {
+        return fmt.Errorf("role %q does not exist", role)
+    }
+    if err != nil {
+        return err
+    }
+
+    query := `
+        INSERT INTO user_roles (user_id, role_id)
+        VALUES ($1, $2)
+        ON CONFLICT (user_id, role_id) DO NOTHING`
+    _, err = conn(ctx, r.db).ExecContext(ctx, query, userID, roleID)
+    return err
+}
@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/francis/projectx-api/internal/repository"
+	"github.com/lib/pq"
+)
+
+// DBTX is the part of *sql.DB and *sql.Tx the repositories use, so the same
+// query code runs inside or outside a transaction.
+type DBTX interface {
+    ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+    QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+    QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+    PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+type txKey struct{}
+
+// conn returns the transaction started by WithinTx for ctx, or db when ctx
+// carries none. Every repository query goes through it.
+func conn(ctx context.Context, db *sql.DB) DBTX {
+    if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+        return tx
+    }
+    return db
+}
+
+// maxTxAttempts bounds how often a transaction is run when Postgres aborts
+// it with a serialization failure or deadlock.
+const maxTxAttempts = 3
+
+type txManager struct {
+    db   *sql.DB
+    opts *sql.TxOptions
+}
+
+// NewTxManager returns a TxManager that starts transactions on db with opts,
+// or with the database defaults when opts is nil.
+func NewTxManager(db *sql.DB, opts *sql.TxOptions) repository.TxManager {
+    return &txManager{db: db, opts: opts}
+}
+
+// WithinTx runs fn in a transaction carried by the context passed to it.
+// Repositories called with that context join the transaction. A call made
+// while a transaction is already open joins it too, and the outermost call
+// decides whether to commit.
+//
+// When Postgres aborts the transaction with a serialization failure or a
+// deadlock, fn is run again from the start, so it must not have side
+// effects outside the database.
+func (m *txManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+    if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+        return fn(ctx)
+    }
+
+    var err error
+    for attempt := 1; attempt <= maxTxAttempts; attempt++ {
+        err = m.run(ctx, fn)
+        if err == nil || !retryable(err) || attempt == maxTxAttempts {
+            break
+        }
+
+        // Back off with jitter so the transactions that collided do not
+        // collide again
+        delay := time.Duration(attempt*10+rand.Intn(10)) * time.Millisecond
+        select {
+        case <-ctx.Done():
+            return err
+        case <-time.After(delay):
+        }
+    }
+    return err
+}
+
+func (m *txManager) run(ctx context.Context, fn func(ctx context.Context) error) error {
+    tx, err := m.db.BeginTx(ctx, m.opts)
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+
+    if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+        return err
+    }
+    return tx.Commit()
+}
+
+// retryable reports whether err is a serialization_failure or
+// deadlock_detected, after which the whole transaction can be run again.
+func retryable(err error) bool {
+    var pqErr *pq.Error
+    if !errors.As(err, &pqErr) {
+        return false
+    }
+    return pqErr.Code == "40001" || pqErr.Code == "40P01"
+}
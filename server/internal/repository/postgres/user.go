@@ -3,10 +3,14 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/francis/projectx-api/internal/model"
 	"github.com/francis/projectx-api/internal/repository"
+	"github.com/francis/projectx-api/pkg/utils"
+	"github.com/lib/pq"
 )
 
 type userRepository struct {
@@ -19,31 +23,61 @@ func NewUserRepository(db *sql.DB) repository.UserRepository {
 
 func (r *userRepository) Create(ctx context.Context, user *model.User) error {
     query := `
-        INSERT INTO users (email, first_name, last_name, password_hash, created_at, updated_at)
-        VALUES ($1, $2, $3, $4, $5, $6)
+        INSERT INTO users (email, username, first_name, last_name, password_hash, password_algo, attributes, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
         RETURNING id`
     
     now := time.Now()
     user.CreatedAt = now
     user.UpdatedAt = now
 
-    return r.db.QueryRowContext(ctx, query,
-        user.Email, user.FirstName, user.LastName, user.Password,
-        user.CreatedAt, user.UpdatedAt).Scan(&user.ID)
+    err := conn(ctx, r.db).QueryRowContext(ctx, query,
+        user.Email, nullIfEmpty(user.Username), user.FirstName, user.LastName, user.Password, passwordAlgo(user),
+        jsonAttributes(user.Attributes), user.CreatedAt, user.UpdatedAt).Scan(&user.ID)
+    return translate(err, "User")
+}
+
+// CreateBatch inserts all users in a single transaction. Either every user
+// is created or none are. Inside WithinTx it joins the caller's transaction.
+func (r *userRepository) CreateBatch(ctx context.Context, users []*model.User) error {
+    return NewTxManager(r.db, nil).WithinTx(ctx, func(ctx context.Context) error {
+        stmt, err := conn(ctx, r.db).PrepareContext(ctx, `
+            INSERT INTO users (email, username, first_name, last_name, password_hash, password_algo, attributes, created_at, updated_at)
+            VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+            RETURNING id`)
+        if err != nil {
+            return err
+        }
+        defer stmt.Close()
+
+        now := time.Now()
+        for _, user := range users {
+            user.CreatedAt = now
+            user.UpdatedAt = now
+            err := stmt.QueryRowContext(ctx,
+                user.Email, nullIfEmpty(user.Username), user.FirstName, user.LastName, user.Password, passwordAlgo(user),
+                jsonAttributes(user.Attributes), user.CreatedAt, user.UpdatedAt).Scan(&user.ID)
+            if err != nil {
+                return fmt.Errorf("insert %s: %w", user.Email, translate(err, "User"))
+            }
+        }
+        return nil
+    })
 }
 
 func (r *userRepository) GetByID(ctx context.Context, id int) (*model.User, error) {
     user := &model.User{}
     query := `
-        SELECT id, email, first_name, last_name, password_hash, created_at, updated_at
+        SELECT id, email, username, first_name, last_name, password_hash, password_algo, attributes, created_at, updated_at
         FROM users WHERE id = $1`
 
-    err := r.db.QueryRowContext(ctx, query, id).Scan(
-        &user.ID, &user.Email, &user.FirstName, &user.LastName,
-        &user.Password, &user.CreatedAt, &user.UpdatedAt)
+    err := conn(ctx, r.db).QueryRowContext(ctx, query, id).Scan(
+        &user.ID, &user.Email, (*nullString)(&user.Username), &user.FirstName, &user.LastName,
+        &user.Password, &user.PasswordAlgo, (*jsonAttributes)(&user.Attributes),
+        &user.CreatedAt, &user.UpdatedAt)
 
     if err != nil {
-        return nil, err
+        return nil, translate(err, "User")
     }
     return user, nil
 }
@@ -51,43 +85,133 @@ func (r *userRepository) GetByID(ctx context.Context, id int) (*model.User, erro
 func (r *userRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
     user := &model.User{}
     query := `
-        SELECT id, email, first_name, last_name, password_hash, created_at, updated_at
+        SELECT id, email, username, first_name, last_name, password_hash, password_algo, attributes, created_at, updated_at
         FROM users WHERE email = $1`
 
-    err := r.db.QueryRowContext(ctx, query, email).Scan(
-        &user.ID, &user.Email, &user.FirstName, &user.LastName,
-        &user.Password, &user.CreatedAt, &user.UpdatedAt)
+    err := conn(ctx, r.db).QueryRowContext(ctx, query, email).Scan(
+        &user.ID, &user.Email, (*nullString)(&user.Username), &user.FirstName, &user.LastName,
+        &user.Password, &user.PasswordAlgo, (*jsonAttributes)(&user.Attributes),
+        &user.CreatedAt, &user.UpdatedAt)
 
     if err != nil {
-        return nil, err
+        return nil, translate(err, "User")
     }
     return user, nil
 }
 
+// GetByUsername looks a user up by username, ignoring case.
+func (r *userRepository) GetByUsername(ctx context.Context, username string) (*model.User, error) {
+    user := &model.User{}
+    query := `
+        SELECT id, email, username, first_name, last_name, password_hash, password_algo, attributes, created_at, updated_at
+        FROM users WHERE LOWER(username) = LOWER($1)`
+
+    err := conn(ctx, r.db).QueryRowContext(ctx, query, username).Scan(
+        &user.ID, &user.Email, (*nullString)(&user.Username), &user.FirstName, &user.LastName,
+        &user.Password, &user.PasswordAlgo, (*jsonAttributes)(&user.Attributes),
+        &user.CreatedAt, &user.UpdatedAt)
+
+    if err != nil {
+        return nil, translate(err, "User")
+    }
+    return user, nil
+}
+
+// ExistingEmails reports which of the given emails already belong to a user,
+// ignoring case. emails must be lowercase.
+func (r *userRepository) ExistingEmails(ctx context.Context, emails []string) (map[string]bool, error) {
+    existing := make(map[string]bool)
+    if len(emails) == 0 {
+        return existing, nil
+    }
+
+    query := `SELECT LOWER(email) FROM users WHERE LOWER(email) = ANY($1)`
+    rows, err := conn(ctx, r.db).QueryContext(ctx, query, pq.Array(emails))
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    for rows.Next() {
+        var email string
+        if err := rows.Scan(&email); err != nil {
+            return nil, err
+        }
+        existing[email] = true
+    }
+    return existing, rows.Err()
+}
+
+// ExistingUsernames reports which of the given usernames are taken, ignoring
+// case. usernames must be lowercase.
+func (r *userRepository) ExistingUsernames(ctx context.Context, usernames []string) (map[string]bool, error) {
+    existing := make(map[string]bool)
+    if len(usernames) == 0 {
+        return existing, nil
+    }
+
+    query := `SELECT LOWER(username) FROM users WHERE LOWER(username) = ANY($1)`
+    rows, err := conn(ctx, r.db).QueryContext(ctx, query, pq.Array(usernames))
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    for rows.Next() {
+        var username string
+        if err := rows.Scan(&username); err != nil {
+            return nil, err
+        }
+        existing[username] = true
+    }
+    return existing, rows.Err()
+}
+
+// Update saves the user's profile. Username and attributes are replaced when
+// set and left untouched otherwise.
 func (r *userRepository) Update(ctx context.Context, user *model.User) error {
     query := `
         UPDATE users 
-        SET email = $2, first_name = $3, last_name = $4, updated_at = $5
+        SET email = $2, first_name = $3, last_name = $4, updated_at = $5,
+            attributes = COALESCE($6, attributes), username = COALESCE($7, username)
         WHERE id = $1`
     
+    var attributes interface{}
+    if user.Attributes != nil {
+        attributes = jsonAttributes(user.Attributes)
+    }
+
     user.UpdatedAt = time.Now()
-    _, err := r.db.ExecContext(ctx, query,
-        user.ID, user.Email, user.FirstName, user.LastName, user.UpdatedAt)
-    return err
+    result, err := conn(ctx, r.db).ExecContext(ctx, query,
+        user.ID, user.Email, user.FirstName, user.LastName, user.UpdatedAt, attributes,
+        nullIfEmpty(user.Username))
+    return notFoundUnlessAffected(result, err, "User")
+}
+
+func (r *userRepository) UpdatePassword(ctx context.Context, id int, hash, algo string) error {
+    query := `
+        UPDATE users
+        SET password_hash = $2, password_algo = $3, updated_at = $4
+        WHERE id = $1`
+
+    result, err := conn(ctx, r.db).ExecContext(ctx, query, id, hash, algo, time.Now())
+    return notFoundUnlessAffected(result, err, "User")
 }
 
 func (r *userRepository) Delete(ctx context.Context, id int) error {
     query := `DELETE FROM users WHERE id = $1`
-    _, err := r.db.ExecContext(ctx, query, id)
-    return err
+    result, err := conn(ctx, r.db).ExecContext(ctx, query, id)
+    return notFoundUnlessAffected(result, err, "User")
 }
 
-func (r *userRepository) List(ctx context.Context, limit, offset int) ([]*model.User, error) {
-    query := `
-        SELECT id, email, first_name, last_name, created_at, updated_at
-        FROM users ORDER BY created_at DESC LIMIT $1 OFFSET $2`
+func (r *userRepository) List(ctx context.Context, filter model.UserFilter, limit, offset int) ([]*model.User, error) {
+    where, args := buildUserFilter(filter)
+    args = append(args, limit, offset)
+    query := fmt.Sprintf(`
+        SELECT id, email, username, first_name, last_name, attributes, created_at, updated_at
+        FROM users%s ORDER BY created_at DESC LIMIT $%d OFFSET $%d`, where, len(args)-1, len(args))
 
-    rows, err := r.db.QueryContext(ctx, query, limit, offset)
+    rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
     if err != nil {
         return nil, err
     }
@@ -96,8 +220,8 @@ func (r *userRepository) List(ctx context.Context, limit, offset int) ([]*model.
     var users []*model.User
     for rows.Next() {
         user := &model.User{}
-        err := rows.Scan(&user.ID, &user.Email, &user.FirstName,
-            &user.LastName, &user.CreatedAt, &user.UpdatedAt)
+        err := rows.Scan(&user.ID, &user.Email, (*nullString)(&user.Username), &user.FirstName, &user.LastName,
+            (*jsonAttributes)(&user.Attributes), &user.CreatedAt, &user.UpdatedAt)
         if err != nil {
             return nil, err
         }
@@ -106,9 +230,142 @@ func (r *userRepository) List(ctx context.Context, limit, offset int) ([]*model.
     return users, nil
 }
 
-func (r *userRepository) Count(ctx context.Context) (int64, error) {
+// Stream calls fn for every user matching filter, oldest first, without
+// loading the whole result set into memory.
+func (r *userRepository) Stream(ctx context.Context, filter model.UserFilter, fn func(*model.User) error) error {
+    where, args := buildUserFilter(filter)
+    query := `
+        SELECT id, email, username, first_name, last_name, attributes, created_at, updated_at
+        FROM users` + where + ` ORDER BY id`
+
+    rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+    if err != nil {
+        return err
+    }
+    defer rows.Close()
+
+    for rows.Next() {
+        user := &model.User{}
+        err := rows.Scan(&user.ID, &user.Email, (*nullString)(&user.Username), &user.FirstName, &user.LastName,
+            (*jsonAttributes)(&user.Attributes), &user.CreatedAt, &user.UpdatedAt)
+        if err != nil {
+            return err
+        }
+        if err := fn(user); err != nil {
+            return err
+        }
+    }
+    return rows.Err()
+}
+
+func passwordAlgo(user *model.User) string {
+    if user.PasswordAlgo == "" {
+        return utils.HashBcrypt
+    }
+    return user.PasswordAlgo
+}
+
+func buildUserFilter(filter model.UserFilter) (string, []interface{}) {
+    var conditions []string
+    var args []interface{}
+
+    if filter.Email != "" {
+        args = append(args, "%"+filter.Email+"%")
+        conditions = append(conditions, fmt.Sprintf("email ILIKE $%d", len(args)))
+    }
+    if filter.CreatedAfter != nil {
+        args = append(args, *filter.CreatedAfter)
+        conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+    }
+    if filter.CreatedBefore != nil {
+        args = append(args, *filter.CreatedBefore)
+        conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+    }
+    if len(filter.Attributes) > 0 {
+        // Containment is served by the jsonb_path_ops GIN index.
+        args = append(args, jsonAttributes(filter.Attributes))
+        conditions = append(conditions, fmt.Sprintf("attributes @> $%d", len(args)))
+    }
+
+    if len(conditions) == 0 {
+        return "", nil
+    }
+    return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+func (r *userRepository) Count(ctx context.Context, filter model.UserFilter) (int64, error) {
     var count int64
-    query := `SELECT COUNT(*) FROM users`
-    err := r.db.QueryRowContext(ctx, query).Scan(&count)
+    where, args := buildUserFilter(filter)
+    query := `SELECT COUNT(*) FROM users` + where
+    err := conn(ctx, r.db).QueryRowContext(ctx, query, args...).Scan(&count)
     return count, err
+}
+
+func (r *userRepository) Search(ctx context.Context, query string, limit, offset int) ([]*model.UserSearchResult, int64, error) {
+    terms := repository.SearchTerms(query)
+    if len(terms) == 0 {
+        return []*model.UserSearchResult{}, 0, nil
+    }
+
+    // Every term is matched as a prefix so partial input like "jon smi"
+    // finds "Jonathan Smith".
+    prefixes := make([]string, len(terms))
+    for i, term := range terms {
+        prefixes[i] = term + ":*"
+    }
+    tsquery := strings.Join(prefixes, " & ")
+
+    headline := fmt.Sprintf("StartSel=%s, StopSel=%s, HighlightAll=true", repository.HighlightStart, repository.HighlightStop)
+    sqlQuery := `
+        WITH q AS (
+            SELECT to_tsquery('simple', $1) AS query, $2::text AS raw
+        )
+        SELECT id, email, username, first_name, last_name, attributes, created_at, updated_at,
+               ts_rank(search_vector, q.query) +
+                   similarity(first_name || ' ' || last_name || ' ' || email, q.raw) AS rank,
+               ts_headline('simple', translate(first_name || ' ' || last_name, $6, ''), q.query, $3),
+               ts_headline('simple', translate(email, $6, ''), q.query, $3),
+               COUNT(*) OVER () AS total
+        FROM users, q
+        WHERE search_vector @@ q.query
+           OR (first_name || ' ' || last_name || ' ' || email) % q.raw
+        ORDER BY rank DESC, id
+        LIMIT $4 OFFSET $5`
+
+    rows, err := conn(ctx, r.db).QueryContext(ctx, sqlQuery, tsquery, strings.Join(terms, " "), headline, limit, offset, repository.HighlightMarkers)
+    if err != nil {
+        return nil, 0, err
+    }
+    defer rows.Close()
+
+    results := []*model.UserSearchResult{}
+    var total int64
+    for rows.Next() {
+        result := &model.UserSearchResult{}
+        err := rows.Scan(&result.ID, &result.Email, (*nullString)(&result.Username), &result.FirstName, &result.LastName,
+            (*jsonAttributes)(&result.Attributes), &result.CreatedAt, &result.UpdatedAt, &result.Rank,
+            &result.Highlight.Name, &result.Highlight.Email, &total)
+        if err != nil {
+            return nil, 0, err
+        }
+        result.Highlight.Name = repository.RenderHighlight(result.Highlight.Name)
+        result.Highlight.Email = repository.RenderHighlight(result.Highlight.Email)
+        results = append(results, result)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, 0, err
+    }
+
+    // COUNT(*) OVER () is only available when the page is not empty.
+    if len(results) == 0 && offset > 0 {
+        countQuery := `
+            SELECT COUNT(*) FROM users
+            WHERE search_vector @@ to_tsquery('simple', $1)
+               OR (first_name || ' ' || last_name || ' ' || email) % $2`
+        if err := conn(ctx, r.db).QueryRowContext(ctx, countQuery, tsquery, strings.Join(terms, " ")).Scan(&total); err != nil {
+            return nil, 0, err
+        }
+    }
+
+    return results, total, nil
 }
\ No newline at end of file
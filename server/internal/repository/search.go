@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// Implementations mark highlighted terms with these control characters and
+// convert them with RenderHighlight. Nothing stops users saving them in a
+// name, so they are removed from the text with StripHighlight, or with
+// translate(text, HighlightMarkers, '') in SQL, before it is highlighted.
+const (
+    HighlightStart   = "\x02"
+    HighlightStop    = "\x03"
+    HighlightMarkers = HighlightStart + HighlightStop
+)
+
+// SearchTerms splits a free-text query into lowercase terms, dropping
+// punctuation so the terms are safe to use in a tsquery.
+func SearchTerms(query string) []string {
+    return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+        return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+    })
+}
+
+// StripHighlight removes highlight markers from text about to be highlighted.
+func StripHighlight(s string) string {
+    return strings.Map(func(r rune) rune {
+        if strings.ContainsRune(HighlightMarkers, r) {
+            return -1
+        }
+        return r
+    }, s)
+}
+
+// RenderHighlight HTML-escapes s and turns highlight markers into <mark> tags.
+func RenderHighlight(s string) string {
+    s = html.EscapeString(s)
+    s = strings.ReplaceAll(s, HighlightStart, "<mark>")
+    return strings.ReplaceAll(s, HighlightStop, "</mark>")
+}
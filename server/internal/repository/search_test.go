@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSearchTerms(t *testing.T) {
+    tests := []struct {
+        query string
+        want  []string
+    }{
+        {"", []string{}},
+        {"Jon Smith", []string{"jon", "smith"}},
+        {"jon.smith@example.com", []string{"jon", "smith", "example", "com"}},
+        {"o'brien & (x | !y):*", []string{"o", "brien", "x", "y"}},
+        {"  Zoë  ", []string{"zoë"}},
+        {"\x02jon\x03", []string{"jon"}},
+    }
+
+    for _, tt := range tests {
+        if got := SearchTerms(tt.query); !reflect.DeepEqual(got, tt.want) {
+            t.Errorf("SearchTerms(%q) = %q, want %q", tt.query, got, tt.want)
+        }
+    }
+}
+
+func TestRenderHighlight(t *testing.T) {
+    tests := []struct {
+        name string
+        in   string
+        want string
+    }{
+        {"plain", "Jon Smith", "Jon Smith"},
+        {"marked", "\x02Jon\x03 Smith", "<mark>Jon</mark> Smith"},
+        {"escaped", "\x02<b>\x03 & co", "<mark>&lt;b&gt;</mark> &amp; co"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := RenderHighlight(tt.in); got != tt.want {
+                t.Errorf("RenderHighlight(%q) = %q, want %q", tt.in, got, tt.want)
+            }
+        })
+    }
+}
+
+func TestStripHighlightKeepsUserMarkersOutOfRenderedHTML(t *testing.T) {
+    // A user who saves markers in their name must not be able to inject
+    // <mark> tags, or unbalance the ones the search adds
+    name := "\x03</mark><script>\x02x"
+    got := RenderHighlight(StripHighlight(name))
+    want := "&lt;/mark&gt;&lt;script&gt;x"
+    if got != want {
+        t.Errorf("RenderHighlight(StripHighlight(%q)) = %q, want %q", name, got, want)
+    }
+}
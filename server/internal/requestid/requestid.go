@@ -0,0 +1,22 @@
+// Package requestid holds the ID the RequestID middleware gives every
+// request, so error responses, logs and traces can quote it.
+package requestid
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// Header carries the request ID in both directions.
+const Header = "X-Request-ID"
+
+// key is where the ID is kept in the gin context.
+const key = "request_id"
+
+func Set(c *gin.Context, id string) {
+    c.Set(key, id)
+}
+
+// Get returns the request's ID, or "" before the RequestID middleware ran.
+func Get(c *gin.Context) string {
+    return c.GetString(key)
+}
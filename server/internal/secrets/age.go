@@ -0,0 +1,74 @@
+package secrets
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/joho/godotenv"
+)
+
+// ageProvider serves secrets from an age-encrypted file of KEY=VALUE lines,
+// in the same format as .env. The file is decrypted once, when the provider
+// is created, so the plaintext never touches the disk.
+type ageProvider struct {
+    values map[string]string
+}
+
+// NewAgeProvider decrypts path with the identities (private keys) in
+// identityPath. Both binary and ASCII-armored files are accepted. Create the
+// file with, for example:
+//
+//	age -r age1... -o secrets.env.age secrets.env
+func NewAgeProvider(path, identityPath string) (Provider, error) {
+    keys, err := os.Open(identityPath)
+    if err != nil {
+        return nil, fmt.Errorf("open age identity file: %w", err)
+    }
+    defer keys.Close()
+
+    identities, err := age.ParseIdentities(keys)
+    if err != nil {
+        return nil, fmt.Errorf("parse age identity file: %w", err)
+    }
+
+    f, err := os.Open(path)
+    if err != nil {
+        return nil, fmt.Errorf("open age secrets file: %w", err)
+    }
+    defer f.Close()
+
+    br := bufio.NewReader(f)
+    var src io.Reader = br
+    if head, _ := br.Peek(len(armor.Header)); string(head) == armor.Header {
+        src = armor.NewReader(br)
+    }
+
+    plaintext, err := age.Decrypt(src, identities...)
+    if err != nil {
+        return nil, fmt.Errorf("decrypt %s: %w", path, err)
+    }
+    data, err := io.ReadAll(plaintext)
+    if err != nil {
+        return nil, fmt.Errorf("decrypt %s: %w", path, err)
+    }
+
+    values, err := godotenv.Parse(bytes.NewReader(data))
+    if err != nil {
+        return nil, fmt.Errorf("parse %s: %w", path, err)
+    }
+    return &ageProvider{values: values}, nil
+}
+
+func (p *ageProvider) Get(ctx context.Context, name string) (string, error) {
+    value, ok := p.values[name]
+    if !ok {
+        return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+    }
+    return value, nil
+}
@@ -0,0 +1,32 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fileProvider reads each secret from a file of the same name in dir, which
+// is how Docker (/run/secrets) and Kubernetes volume mounts expose them.
+type fileProvider struct {
+    dir string
+}
+
+func NewFileProvider(dir string) Provider {
+    return &fileProvider{dir: dir}
+}
+
+func (p *fileProvider) Get(ctx context.Context, name string) (string, error) {
+    if name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+        return "", fmt.Errorf("invalid secret name %q", name)
+    }
+
+    value, err := ReadFile(filepath.Join(p.dir, name))
+    if errors.Is(err, os.ErrNotExist) {
+        return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+    }
+    return value, err
+}
@@ -0,0 +1,29 @@
+// Package secrets reads secret values from places other than plain
+// environment variables: mounted secret files and encrypted stores.
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var ErrNotFound = errors.New("secret not found")
+
+// Provider looks up secrets by name in an external store.
+type Provider interface {
+    Get(ctx context.Context, name string) (string, error)
+}
+
+// ReadFile reads a secret file as mounted by Docker or Kubernetes. A single
+// trailing newline, as left by most editors and `echo`, is dropped.
+func ReadFile(path string) (string, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return "", fmt.Errorf("read secret file: %w", err)
+    }
+    value := strings.TrimSuffix(string(data), "\n")
+    return strings.TrimSuffix(value, "\r"), nil
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/francis/projectx-api/internal/apperror"
+	"github.com/francis/projectx-api/internal/model"
+	"github.com/francis/projectx-api/internal/repository"
+	"github.com/francis/projectx-api/internal/tracing"
+	"github.com/francis/projectx-api/pkg/validator"
+)
+
+// errOrgNotEditable rejects users setting their own organization. Feature
+// flags target organizations, so only the admin import may set it.
+var errOrgNotEditable = apperror.Forbidden("the %s attribute can only be set by an administrator", model.OrgAttribute).WithCode("attribute_not_editable")
+
+type AttributeService struct {
+    attrRepo repository.AttributeRepository
+}
+
+func NewAttributeService(attrRepo repository.AttributeRepository) *AttributeService {
+    return &AttributeService{attrRepo: attrRepo}
+}
+
+func (s *AttributeService) List(ctx context.Context) ([]*model.AttributeDefinition, error) {
+    ctx, span := tracing.Start(ctx, "AttributeService.List")
+    defer span.End()
+
+    return s.attrRepo.List(ctx)
+}
+
+func (s *AttributeService) Create(ctx context.Context, def *model.AttributeDefinition) error {
+    ctx, span := tracing.Start(ctx, "AttributeService.Create")
+    defer span.End()
+
+    if err := validator.Validate(def); err != nil {
+        return apperror.Invalid(err)
+    }
+    return s.attrRepo.Create(ctx, def)
+}
+
+func (s *AttributeService) Update(ctx context.Context, def *model.AttributeDefinition) error {
+    ctx, span := tracing.Start(ctx, "AttributeService.Update")
+    defer span.End()
+
+    if err := validator.Validate(def); err != nil {
+        return apperror.Invalid(err)
+    }
+    return s.attrRepo.Update(ctx, def)
+}
+
+func (s *AttributeService) Delete(ctx context.Context, key string) error {
+    ctx, span := tracing.Start(ctx, "AttributeService.Delete")
+    defer span.End()
+
+    return s.attrRepo.Delete(ctx, key)
+}
+
+// Validate checks attribute values against the current definitions.
+func (s *AttributeService) Validate(ctx context.Context, values model.Attributes) error {
+    ctx, span := tracing.Start(ctx, "AttributeService.Validate")
+    defer span.End()
+
+    defs, err := s.attrRepo.List(ctx)
+    if err != nil {
+        return err
+    }
+    return apperror.Invalid(validator.ValidateAttributes(rulesFor(defs), values))
+}
+
+// ParseFilter converts attribute filters taken from a query string into
+// values of the defined attribute types, so they can be matched against the
+// stored JSON.
+func (s *AttributeService) ParseFilter(ctx context.Context, raw map[string]string) (model.Attributes, error) {
+    if len(raw) == 0 {
+        return nil, nil
+    }
+
+    defs, err := s.attrRepo.List(ctx)
+    if err != nil {
+        return nil, err
+    }
+    byKey := make(map[string]*model.AttributeDefinition, len(defs))
+    for _, def := range defs {
+        byKey[def.Key] = def
+    }
+
+    filter := make(model.Attributes, len(raw))
+    for key, value := range raw {
+        def, ok := byKey[key]
+        if !ok {
+            return nil, apperror.Validation("%s is not a defined attribute", key).WithCode("unknown_attribute")
+        }
+        typed, err := parseAttributeValue(def, value)
+        if err != nil {
+            return nil, apperror.Invalid(err)
+        }
+        filter[key] = typed
+    }
+    return filter, nil
+}
+
+// parseAttributeValue converts the text form of a value, as found in query
+// strings and CSV files, to the JSON type of its definition.
+func parseAttributeValue(def *model.AttributeDefinition, value string) (interface{}, error) {
+    switch def.Type {
+    case model.AttributeTypeNumber:
+        n, err := strconv.ParseFloat(value, 64)
+        if err != nil {
+            return nil, fmt.Errorf("%s must be a number", def.Key)
+        }
+        return n, nil
+    case model.AttributeTypeBoolean:
+        b, err := strconv.ParseBool(value)
+        if err != nil {
+            return nil, fmt.Errorf("%s must be true or false", def.Key)
+        }
+        return b, nil
+    default:
+        return value, nil
+    }
+}
+
+func rulesFor(defs []*model.AttributeDefinition) []validator.AttributeRule {
+    rules := make([]validator.AttributeRule, len(defs))
+    for i, def := range defs {
+        rules[i] = validator.AttributeRule{
+            Key:      def.Key,
+            Type:     def.Type,
+            Required: def.Required,
+            Enum:     def.EnumValues,
+            Pattern:  def.Pattern,
+        }
+    }
+    return rules
+}
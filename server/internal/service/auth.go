@@ -3,47 +3,101 @@ package service
 import (
 	"context"
 	"errors"
+	"strings"
 	"time"
 
+	"github.com/francis/projectx-api/internal/apperror"
+	"github.com/francis/projectx-api/internal/metrics"
 	"github.com/francis/projectx-api/internal/model"
 	"github.com/francis/projectx-api/internal/repository"
+	"github.com/francis/projectx-api/internal/tracing"
+	"github.com/francis/projectx-api/pkg/logger"
 	"github.com/francis/projectx-api/pkg/utils"
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// errInvalidCredentials is returned for an unknown user and a wrong password
+// alike, so the response does not reveal which accounts exist.
+var errInvalidCredentials = apperror.Unauthorized("invalid credentials").WithCode("invalid_credentials")
+
+// TokenConfig holds the JWT signing secret and token lifetimes.
+type TokenConfig struct {
+    Secret     string
+    AccessTTL  time.Duration
+    RefreshTTL time.Duration
+}
+
 type AuthService struct {
-    userRepo  repository.UserRepository
-    jwtSecret string
+    tx         repository.TxManager
+    userRepo   repository.UserRepository
+    roleRepo   repository.RoleRepository
+    attributes *AttributeService
+    tokens     TokenConfig
+    legacyHash utils.LegacyHashParams
 }
 
-func NewAuthService(userRepo repository.UserRepository, jwtSecret string) *AuthService {
+func NewAuthService(tx repository.TxManager, userRepo repository.UserRepository, roleRepo repository.RoleRepository, attributes *AttributeService, tokens TokenConfig, legacyHash utils.LegacyHashParams) *AuthService {
     return &AuthService{
-        userRepo:  userRepo,
-        jwtSecret: jwtSecret,
+        tx:         tx,
+        userRepo:   userRepo,
+        roleRepo:   roleRepo,
+        attributes: attributes,
+        tokens:     tokens,
+        legacyHash: legacyHash,
     }
 }
 
+// Register creates the user and gives them the user role in one
+// transaction, so a failure part way leaves no account without a role.
 func (s *AuthService) Register(ctx context.Context, req *model.CreateUserRequest) (*model.User, error) {
-    // Check if user exists
-    existingUser, _ := s.userRepo.GetByEmail(ctx, req.Email)
-    if existingUser != nil {
-        return nil, errors.New("user already exists")
+    ctx, span := tracing.Start(ctx, "AuthService.Register")
+    defer span.End()
+
+    if _, ok := req.Attributes[model.OrgAttribute]; ok {
+        return nil, errOrgNotEditable
+    }
+    if err := s.attributes.Validate(ctx, req.Attributes); err != nil {
+        return nil, err
     }
 
     // Hash password
-    hashedPassword, err := utils.HashPassword(req.Password)
+    hashedPassword, err := hashPassword(ctx, req.Password)
     if err != nil {
         return nil, err
     }
 
     user := &model.User{
-        Email:     req.Email,
-        FirstName: req.FirstName,
-        LastName:  req.LastName,
-        Password:  hashedPassword,
+        Email:      req.Email,
+        Username:   req.Username,
+        FirstName:  req.FirstName,
+        LastName:   req.LastName,
+        Password:   hashedPassword,
+        Attributes: req.Attributes,
     }
 
-    if err := s.userRepo.Create(ctx, user); err != nil {
+    err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+        // Check if user exists. Two registrations racing past these checks
+        // are caught by the unique constraints, which also report Conflict.
+        if _, err := s.userRepo.GetByEmail(ctx, req.Email); err == nil {
+            return apperror.Conflict("user already exists").WithCode("email_taken")
+        } else if !errors.Is(err, apperror.ErrNotFound) {
+            return err
+        }
+
+        if req.Username != "" {
+            if _, err := s.userRepo.GetByUsername(ctx, req.Username); err == nil {
+                return apperror.Conflict("username is already taken").WithCode("username_taken")
+            } else if !errors.Is(err, apperror.ErrNotFound) {
+                return err
+            }
+        }
+
+        if err := s.userRepo.Create(ctx, user); err != nil {
+            return err
+        }
+        return s.roleRepo.AssignRole(ctx, user.ID, model.RoleUser)
+    })
+    if err != nil {
         return nil, err
     }
 
@@ -51,16 +105,54 @@ func (s *AuthService) Register(ctx context.Context, req *model.CreateUserRequest
 }
 
 func (s *AuthService) Login(ctx context.Context, req *model.LoginRequest) (*model.LoginResponse, error) {
-    user, err := s.userRepo.GetByEmail(ctx, req.Email)
+    ctx, span := tracing.Start(ctx, "AuthService.Login")
+    defer span.End()
+
+    response, err := s.login(ctx, req)
+    metrics.LoginAttempts.WithLabelValues(loginResult(err)).Inc()
+    return response, err
+}
+
+// loginResult is the result label of the login attempts metric.
+func loginResult(err error) string {
+    switch {
+    case err == nil:
+        return "success"
+    case errors.Is(err, errInvalidCredentials):
+        return "invalid_credentials"
+    }
+    return "error"
+}
+
+func (s *AuthService) login(ctx context.Context, req *model.LoginRequest) (*model.LoginResponse, error) {
+    user, err := s.findLoginUser(ctx, req)
+    if errors.Is(err, apperror.ErrNotFound) {
+        return nil, errInvalidCredentials
+    }
     if err != nil {
-        return nil, errors.New("invalid credentials")
+        return nil, err
     }
 
-    if !utils.CheckPasswordHash(req.Password, user.Password) {
-        return nil, errors.New("invalid credentials")
+    valid, err := checkPassword(ctx, user.PasswordAlgo, req.Password, user.Password, s.legacyHash)
+    if err != nil {
+        return nil, err
+    }
+    if !valid {
+        return nil, errInvalidCredentials
+    }
+
+    // The password is correct, so a failed upgrade must not fail the login;
+    // the legacy hash is tried again next time
+    if err := s.upgradePasswordHash(ctx, user, req.Password); err != nil {
+        logger.FromContext(ctx).Error("Failed to upgrade password hash", err, "user_id", user.ID, "algorithm", user.PasswordAlgo)
     }
 
-    token, err := s.generateToken(user.ID, user.Email)
+    roles, err := s.roleRepo.GetUserRoles(ctx, user.ID)
+    if err != nil {
+        return nil, err
+    }
+
+    token, err := s.generateToken(user, roles)
     if err != nil {
         return nil, err
     }
@@ -74,30 +166,66 @@ func (s *AuthService) Login(ctx context.Context, req *model.LoginRequest) (*mode
         Token:        token,
         RefreshToken: refreshToken,
         User:         *user,
+        Roles:        roles,
     }, nil
 }
 
-func (s *AuthService) generateToken(userID int, email string) (string, error) {
+func (s *AuthService) findLoginUser(ctx context.Context, req *model.LoginRequest) (*model.User, error) {
+    switch {
+    case req.Email != "":
+        return s.userRepo.GetByEmail(ctx, req.Email)
+    case strings.Contains(req.Username, "@"):
+        return s.userRepo.GetByEmail(ctx, req.Username)
+    default:
+        return s.userRepo.GetByUsername(ctx, req.Username)
+    }
+}
+
+// upgradePasswordHash replaces a hash imported from another system with a
+// native bcrypt hash, now that the plaintext password is known to be correct.
+func (s *AuthService) upgradePasswordHash(ctx context.Context, user *model.User, password string) error {
+    if user.PasswordAlgo == "" || user.PasswordAlgo == utils.HashBcrypt {
+        return nil
+    }
+
+    hashedPassword, err := hashPassword(ctx, password)
+    if err != nil {
+        return err
+    }
+    if err := s.userRepo.UpdatePassword(ctx, user.ID, hashedPassword, utils.HashBcrypt); err != nil {
+        return err
+    }
+
+    user.Password = hashedPassword
+    user.PasswordAlgo = utils.HashBcrypt
+    return nil
+}
+
+func (s *AuthService) generateToken(user *model.User, roles []string) (string, error) {
     claims := jwt.MapClaims{
-        "user_id": userID,
-        "email":   email,
-        "exp":     time.Now().Add(time.Hour * 24).Unix(),
+        "user_id": user.ID,
+        "email":   user.Email,
+        "roles":   roles,
+        "exp":     time.Now().Add(s.tokens.AccessTTL).Unix(),
         "iat":     time.Now().Unix(),
     }
+    if org, ok := user.Attributes[model.OrgAttribute].(string); ok && org != "" {
+        claims["org"] = org
+    }
 
     token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-    return token.SignedString([]byte(s.jwtSecret))
+    return token.SignedString([]byte(s.tokens.Secret))
 }
 
 func (s *AuthService) generateRefreshToken(userID int) (string, error) {
     claims := jwt.MapClaims{
         "user_id": userID,
-        "exp":     time.Now().Add(time.Hour * 24 * 7).Unix(), // 7 days
+        "exp":     time.Now().Add(s.tokens.RefreshTTL).Unix(),
         "iat":     time.Now().Unix(),
     }
 
     token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-    return token.SignedString([]byte(s.jwtSecret))
+    return token.SignedString([]byte(s.tokens.Secret))
 }
 
 func (s *AuthService) ValidateToken(tokenString string) (*jwt.Token, error) {
@@ -105,6 +233,6 @@ func (s *AuthService) ValidateToken(tokenString string) (*jwt.Token, error) {
         if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
             return nil, errors.New("invalid token")
         }
-        return []byte(s.jwtSecret), nil
+        return []byte(s.tokens.Secret), nil
     })
 }
\ No newline at end of file
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"hash/fnv"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/francis/projectx-api/internal/apperror"
+	"github.com/francis/projectx-api/internal/identity"
+	"github.com/francis/projectx-api/internal/model"
+	"github.com/francis/projectx-api/internal/repository"
+	"github.com/francis/projectx-api/internal/tracing"
+	"github.com/francis/projectx-api/pkg/validator"
+)
+
+// FlagService evaluates feature flags against an in-memory copy of the
+// feature_flags table, so checking a flag never costs a query. The copy is
+// refreshed after every change made here and, for changes made by other
+// instances, when the database sends a notification.
+//
+// Flags that are not in the table fall back to the features section of
+// the config file.
+type FlagService struct {
+    flagRepo repository.FlagRepository
+    flags    atomic.Pointer[map[string]*model.FeatureFlag]
+    defaults atomic.Pointer[map[string]bool]
+}
+
+func NewFlagService(flagRepo repository.FlagRepository, defaults map[string]bool) *FlagService {
+    s := &FlagService{flagRepo: flagRepo}
+    s.flags.Store(&map[string]*model.FeatureFlag{})
+    s.SetDefaults(defaults)
+    return s
+}
+
+// SetDefaults replaces the values used for flags missing from the table.
+func (s *FlagService) SetDefaults(defaults map[string]bool) {
+    s.defaults.Store(&defaults)
+}
+
+// Refresh reloads every flag from the database.
+func (s *FlagService) Refresh(ctx context.Context) error {
+    ctx, span := tracing.Start(ctx, "FlagService.Refresh")
+    defer span.End()
+
+    flags, err := s.flagRepo.List(ctx)
+    if err != nil {
+        return err
+    }
+
+    byName := make(map[string]*model.FeatureFlag, len(flags))
+    for _, flag := range flags {
+        byName[flag.Name] = flag
+    }
+    s.flags.Store(&byName)
+    return nil
+}
+
+// Enabled reports whether the named flag is on for the caller in ctx.
+func (s *FlagService) Enabled(ctx context.Context, name string) bool {
+    flag, ok := (*s.flags.Load())[name]
+    if !ok {
+        return (*s.defaults.Load())[name]
+    }
+
+    caller, _ := identity.FromContext(ctx)
+    return evaluateFlag(flag, caller)
+}
+
+// Evaluate returns the state of every known flag for the caller in ctx, for
+// clients that hide or show features themselves.
+func (s *FlagService) Evaluate(ctx context.Context) map[string]bool {
+    caller, _ := identity.FromContext(ctx)
+
+    result := make(map[string]bool)
+    for name, enabled := range *s.defaults.Load() {
+        result[name] = enabled
+    }
+    for name, flag := range *s.flags.Load() {
+        result[name] = evaluateFlag(flag, caller)
+    }
+    return result
+}
+
+func evaluateFlag(flag *model.FeatureFlag, caller identity.Identity) bool {
+    if !flag.Enabled {
+        return false
+    }
+    if len(flag.Rules) == 0 {
+        return true
+    }
+    for _, rule := range flag.Rules {
+        if matchRule(flag.Name, rule, caller) {
+            return true
+        }
+    }
+    return false
+}
+
+// matchRule reports whether caller meets every condition of rule. Anonymous
+// callers (UserID 0) only match rules without user conditions.
+func matchRule(name string, rule model.FlagRule, caller identity.Identity) bool {
+    if len(rule.UserIDs) > 0 && !containsInt(rule.UserIDs, caller.UserID) {
+        return false
+    }
+    if len(rule.Roles) > 0 && !anyRole(rule.Roles, caller) {
+        return false
+    }
+    if len(rule.Orgs) > 0 && (caller.Org == "" || !containsString(rule.Orgs, caller.Org)) {
+        return false
+    }
+    if rule.Percentage != nil && (caller.UserID == 0 || rolloutBucket(name, caller.UserID) >= *rule.Percentage) {
+        return false
+    }
+    return true
+}
+
+// rolloutBucket places a user in one of 100 buckets for a flag. The bucket
+// is stable, so raising a percentage only ever adds users, and it differs
+// between flags, so the same users are not always first.
+func rolloutBucket(name string, userID int) int {
+    h := fnv.New32a()
+    h.Write([]byte(name + ":" + strconv.Itoa(userID)))
+    return int(h.Sum32() % 100)
+}
+
+func anyRole(roles []string, caller identity.Identity) bool {
+    for _, role := range roles {
+        if caller.HasRole(role) {
+            return true
+        }
+    }
+    return false
+}
+
+func containsInt(values []int, v int) bool {
+    for _, value := range values {
+        if value == v {
+            return true
+        }
+    }
+    return false
+}
+
+func containsString(values []string, v string) bool {
+    for _, value := range values {
+        if value == v {
+            return true
+        }
+    }
+    return false
+}
+
+func (s *FlagService) List(ctx context.Context) ([]*model.FeatureFlag, error) {
+    ctx, span := tracing.Start(ctx, "FlagService.List")
+    defer span.End()
+
+    return s.flagRepo.List(ctx)
+}
+
+func (s *FlagService) Get(ctx context.Context, name string) (*model.FeatureFlag, error) {
+    ctx, span := tracing.Start(ctx, "FlagService.Get")
+    defer span.End()
+
+    return s.flagRepo.GetByName(ctx, name)
+}
+
+func (s *FlagService) Create(ctx context.Context, flag *model.FeatureFlag) error {
+    ctx, span := tracing.Start(ctx, "FlagService.Create")
+    defer span.End()
+
+    if err := validateFlag(flag); err != nil {
+        return err
+    }
+    if err := s.flagRepo.Create(ctx, flag); err != nil {
+        return err
+    }
+    s.refreshAfterWrite(ctx)
+    return nil
+}
+
+func (s *FlagService) Update(ctx context.Context, flag *model.FeatureFlag) error {
+    ctx, span := tracing.Start(ctx, "FlagService.Update")
+    defer span.End()
+
+    if err := validateFlag(flag); err != nil {
+        return err
+    }
+    if err := s.flagRepo.Update(ctx, flag); err != nil {
+        return err
+    }
+    s.refreshAfterWrite(ctx)
+    return nil
+}
+
+func (s *FlagService) Delete(ctx context.Context, name string) error {
+    ctx, span := tracing.Start(ctx, "FlagService.Delete")
+    defer span.End()
+
+    if err := s.flagRepo.Delete(ctx, name); err != nil {
+        return err
+    }
+    s.refreshAfterWrite(ctx)
+    return nil
+}
+
+// refreshAfterWrite makes a change visible on this instance straight away.
+// A failure is not reported: the write succeeded, and the notification it
+// triggered refreshes the cache again shortly.
+func (s *FlagService) refreshAfterWrite(ctx context.Context) {
+    _ = s.Refresh(ctx)
+}
+
+func validateFlag(flag *model.FeatureFlag) error {
+    if err := validator.Validate(flag); err != nil {
+        return apperror.Invalid(err)
+    }
+    for i, rule := range flag.Rules {
+        if rule.IsEmpty() {
+            return apperror.Validation("rules[%d] must set user_ids, roles, orgs or percentage", i).WithCode("empty_flag_rule")
+        }
+    }
+    return nil
+}
@@ -0,0 +1,535 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/francis/projectx-api/internal/apperror"
+	"github.com/francis/projectx-api/internal/mailer"
+	"github.com/francis/projectx-api/internal/model"
+	"github.com/francis/projectx-api/internal/repository"
+	"github.com/francis/projectx-api/internal/tracing"
+	"github.com/francis/projectx-api/pkg/logger"
+	"github.com/francis/projectx-api/pkg/utils"
+	"github.com/francis/projectx-api/pkg/validator"
+)
+
+const (
+    DefaultImportBatchSize = 100
+    MaxImportRows          = 10000
+)
+
+// staleJobAge is how long a pending or running job may go without an
+// update before FailStaleJobs takes it for one whose process died. Jobs
+// are updated after every batch, which takes seconds.
+const staleJobAge = 15 * time.Minute
+
+const (
+    jobInterruptedReason = "import interrupted by server shutdown"
+    jobStaleReason       = "import stopped without finishing"
+)
+
+// UserImportService runs imports in the background. Shutdown waits for
+// them, so call it before the process exits.
+type UserImportService struct {
+    txManager  repository.TxManager
+    userRepo   repository.UserRepository
+    roleRepo   repository.RoleRepository
+    jobRepo    repository.JobRepository
+    attributes *AttributeService
+    mailer     mailer.Mailer
+
+    // running tracks background imports, which stop early once jobsCtx
+    // is cancelled
+    running    sync.WaitGroup
+    jobsCtx    context.Context
+    cancelJobs context.CancelFunc
+}
+
+func NewUserImportService(txManager repository.TxManager, userRepo repository.UserRepository, roleRepo repository.RoleRepository, jobRepo repository.JobRepository, attributes *AttributeService, mailer mailer.Mailer) *UserImportService {
+    jobsCtx, cancelJobs := context.WithCancel(context.Background())
+    return &UserImportService{
+        txManager:  txManager,
+        userRepo:   userRepo,
+        roleRepo:   roleRepo,
+        jobRepo:    jobRepo,
+        attributes: attributes,
+        mailer:     mailer,
+        jobsCtx:    jobsCtx,
+        cancelJobs: cancelJobs,
+    }
+}
+
+// Shutdown waits for running imports to finish. When ctx ends first they
+// are stopped, marked failed, and ctx's error is returned.
+func (s *UserImportService) Shutdown(ctx context.Context) error {
+    done := make(chan struct{})
+    go func() {
+        s.running.Wait()
+        close(done)
+    }()
+
+    select {
+    case <-done:
+        return nil
+    case <-ctx.Done():
+        s.cancelJobs()
+        <-done
+        return ctx.Err()
+    }
+}
+
+// FailStaleJobs marks as failed the jobs left pending or running by a
+// process that exited without finishing them. Jobs still being run by
+// another instance keep being updated, so they are not touched.
+func (s *UserImportService) FailStaleJobs(ctx context.Context) (int64, error) {
+    ctx, span := tracing.Start(ctx, "UserImportService.FailStaleJobs")
+    defer span.End()
+
+    return s.jobRepo.FailStale(ctx, time.Now().Add(-staleJobAge), jobStaleReason)
+}
+
+// ParseRows decodes an import file. CSV files must start with a header row;
+// columns are matched by name so their order does not matter. Custom
+// attributes are read from columns named "attr.<key>".
+func (s *UserImportService) ParseRows(r io.Reader, format model.ImportFormat) ([]model.ImportUserRow, error) {
+    var rows []model.ImportUserRow
+
+    switch format {
+    case model.ImportFormatJSON:
+        if err := json.NewDecoder(r).Decode(&rows); err != nil {
+            return nil, apperror.Wrap(err, apperror.KindValidation, "invalid JSON: %v", err).WithCode("invalid_import_file")
+        }
+    case model.ImportFormatCSV:
+        parsed, err := parseCSVRows(r)
+        if err != nil {
+            return nil, apperror.Wrap(err, apperror.KindValidation, "").WithCode("invalid_import_file")
+        }
+        rows = parsed
+    default:
+        return nil, apperror.Validation("unsupported import format %q", format).WithCode("unsupported_import_format")
+    }
+
+    if len(rows) == 0 {
+        return nil, apperror.Validation("import file contains no rows").WithCode("empty_import_file")
+    }
+    if len(rows) > MaxImportRows {
+        return nil, apperror.Validation("import file exceeds %d rows", MaxImportRows).WithCode("import_too_large")
+    }
+    return rows, nil
+}
+
+func parseCSVRows(r io.Reader) ([]model.ImportUserRow, error) {
+    reader := csv.NewReader(r)
+    reader.TrimLeadingSpace = true
+
+    header, err := reader.Read()
+    if err != nil {
+        return nil, fmt.Errorf("invalid CSV header: %w", err)
+    }
+
+    columns := make(map[string]int, len(header))
+    for i, name := range header {
+        columns[strings.ToLower(strings.TrimSpace(name))] = i
+    }
+    for _, required := range []string{"email", "first_name", "last_name"} {
+        if _, ok := columns[required]; !ok {
+            return nil, fmt.Errorf("CSV header is missing column %q", required)
+        }
+    }
+
+    field := func(record []string, name string) string {
+        i, ok := columns[name]
+        if !ok || i >= len(record) {
+            return ""
+        }
+        return strings.TrimSpace(record[i])
+    }
+
+    attrColumns := make(map[string]int)
+    for name, i := range columns {
+        if key, ok := strings.CutPrefix(name, "attr."); ok {
+            attrColumns[key] = i
+        }
+    }
+
+    var rows []model.ImportUserRow
+    for {
+        record, err := reader.Read()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            return nil, fmt.Errorf("invalid CSV: %w", err)
+        }
+
+        rows = append(rows, model.ImportUserRow{
+            Email:     field(record, "email"),
+            Username:  field(record, "username"),
+            FirstName: field(record, "first_name"),
+            LastName:  field(record, "last_name"),
+            Password:  field(record, "password"),
+
+            PasswordHash: field(record, "password_hash"),
+            PasswordAlgo: field(record, "password_algo"),
+            PasswordSalt: field(record, "password_salt"),
+        })
+
+        // Values stay strings here and are typed during validation.
+        for key, i := range attrColumns {
+            if i < len(record) && strings.TrimSpace(record[i]) != "" {
+                if rows[len(rows)-1].Attributes == nil {
+                    rows[len(rows)-1].Attributes = model.Attributes{}
+                }
+                rows[len(rows)-1].Attributes[key] = strings.TrimSpace(record[i])
+            }
+        }
+    }
+    return rows, nil
+}
+
+// Validate checks every row without writing anything. It reports field
+// errors, duplicates within the file and emails or usernames that are
+// already taken.
+func (s *UserImportService) Validate(ctx context.Context, rows []model.ImportUserRow, opts model.ImportOptions) (*model.ImportReport, error) {
+    ctx, span := tracing.Start(ctx, "UserImportService.Validate")
+    defer span.End()
+
+    report := &model.ImportReport{Total: len(rows), Errors: []model.RowError{}}
+
+    seen := make(map[string]int, len(rows))
+    seenUsernames := make(map[string]int)
+    emails := make([]string, 0, len(rows))
+    var usernames []string
+    for i := range rows {
+        rows[i].Email = strings.ToLower(strings.TrimSpace(rows[i].Email))
+        emails = append(emails, rows[i].Email)
+        if rows[i].Username != "" {
+            usernames = append(usernames, strings.ToLower(rows[i].Username))
+        }
+    }
+
+    existing, err := s.userRepo.ExistingEmails(ctx, emails)
+    if err != nil {
+        return nil, err
+    }
+    taken, err := s.userRepo.ExistingUsernames(ctx, usernames)
+    if err != nil {
+        return nil, err
+    }
+
+    defs, err := s.attributes.List(ctx)
+    if err != nil {
+        return nil, err
+    }
+    rules := rulesFor(defs)
+
+    for i, row := range rows {
+        rowNum := i + 1
+        username := strings.ToLower(row.Username)
+        var problem string
+
+        switch err := validator.Validate(&row); {
+        case err != nil:
+            problem = err.Error()
+        case row.Password != "" && row.PasswordHash != "":
+            problem = "password and password_hash are mutually exclusive"
+        case row.PasswordAlgo == utils.HashFirebaseScrypt && row.PasswordSalt == "":
+            problem = "password_salt is required for firebase_scrypt hashes"
+        case row.Password == "" && row.PasswordHash == "" && !opts.SendInvites:
+            problem = "password is required unless invitations are sent"
+        case existing[row.Email]:
+            problem = "email is already registered"
+        case seen[row.Email] != 0:
+            problem = fmt.Sprintf("duplicate of row %d", seen[row.Email])
+        case username != "" && taken[username]:
+            problem = "username is already taken"
+        case username != "" && seenUsernames[username] != 0:
+            problem = fmt.Sprintf("username already used by row %d", seenUsernames[username])
+        default:
+            if opts.Format == model.ImportFormatCSV {
+                if err := typeCSVAttributes(defs, rows[i].Attributes); err != nil {
+                    problem = err.Error()
+                    break
+                }
+            }
+            if err := validator.ValidateAttributes(rules, rows[i].Attributes); err != nil {
+                problem = err.Error()
+            }
+        }
+
+        if problem != "" {
+            report.Errors = append(report.Errors, model.RowError{Row: rowNum, Email: row.Email, Error: problem})
+            continue
+        }
+        seen[row.Email] = rowNum
+        if username != "" {
+            seenUsernames[username] = rowNum
+        }
+    }
+
+    report.Invalid = len(report.Errors)
+    report.Valid = report.Total - report.Invalid
+    return report, nil
+}
+
+// typeCSVAttributes converts string values read from CSV cells to the types
+// of their definitions in place. Unknown keys are left for validation.
+func typeCSVAttributes(defs []*model.AttributeDefinition, values model.Attributes) error {
+    for _, def := range defs {
+        raw, ok := values[def.Key].(string)
+        if !ok {
+            continue
+        }
+        typed, err := parseAttributeValue(def, raw)
+        if err != nil {
+            return err
+        }
+        values[def.Key] = typed
+    }
+    return nil
+}
+
+// StartImport validates rows, records a job and inserts the valid rows in
+// the background. The returned job can be polled with GetJob.
+func (s *UserImportService) StartImport(ctx context.Context, createdBy int, rows []model.ImportUserRow, opts model.ImportOptions) (*model.Job, error) {
+    ctx, span := tracing.Start(ctx, "UserImportService.StartImport")
+    defer span.End()
+
+    report, err := s.Validate(ctx, rows, opts)
+    if err != nil {
+        return nil, err
+    }
+    if report.Invalid > 0 && !opts.SkipInvalid {
+        return nil, &ImportValidationError{Report: report}
+    }
+
+    job := &model.Job{
+        Type:      model.JobTypeUserImport,
+        Status:    model.JobStatusPending,
+        Total:     report.Total,
+        CreatedBy: createdBy,
+        Errors:    report.Errors,
+    }
+    if err := s.jobRepo.Create(ctx, job); err != nil {
+        return nil, err
+    }
+
+    invalid := make(map[int]bool, len(report.Errors))
+    for _, rowErr := range report.Errors {
+        invalid[rowErr.Row] = true
+    }
+
+    // The background run mutates its own copy of the job.
+    snapshot := *job
+    snapshot.Errors = append([]model.RowError(nil), job.Errors...)
+    log := logger.FromContext(ctx).With("job_id", job.ID)
+    s.running.Add(1)
+    go func() {
+        defer s.running.Done()
+        s.runImport(log, job, rows, invalid, opts)
+    }()
+
+    return &snapshot, nil
+}
+
+func (s *UserImportService) GetJob(ctx context.Context, id string) (*model.Job, error) {
+    ctx, span := tracing.Start(ctx, "UserImportService.GetJob")
+    defer span.End()
+
+    return s.jobRepo.GetByID(ctx, id)
+}
+
+// runImport outlives the request that started it, so it uses the service's
+// context, which Shutdown cancels. It keeps the request's logger, so its
+// lines carry the ID of the request that started the job.
+func (s *UserImportService) runImport(log logger.Logger, job *model.Job, rows []model.ImportUserRow, invalid map[int]bool, opts model.ImportOptions) {
+    ctx := logger.NewContext(s.jobsCtx, log)
+
+    batchSize := opts.BatchSize
+    if batchSize <= 0 {
+        batchSize = DefaultImportBatchSize
+    }
+
+    job.Status = model.JobStatusRunning
+    job.Processed = len(invalid)
+    job.Failed = len(invalid)
+    s.saveJob(ctx, log, job)
+
+    type pending struct {
+        row      int
+        user     *model.User
+        password string
+        invite   bool
+    }
+    var batch []pending
+
+    flush := func() {
+        if len(batch) == 0 {
+            return
+        }
+
+        users := make([]*model.User, len(batch))
+        for i, p := range batch {
+            users[i] = p.user
+        }
+
+        if err := s.createUsers(ctx, users); err != nil {
+            log.Error("Import batch failed", err)
+            for _, p := range batch {
+                job.Errors = append(job.Errors, model.RowError{Row: p.row, Email: p.user.Email, Error: "batch insert failed"})
+            }
+            job.Failed += len(batch)
+        } else {
+            job.Succeeded += len(batch)
+            for _, p := range batch {
+                if p.invite {
+                    s.sendInvite(ctx, log, p.user, p.password)
+                }
+            }
+        }
+
+        job.Processed += len(batch)
+        batch = batch[:0]
+        s.saveJob(ctx, log, job)
+    }
+
+    for i, row := range rows {
+        if ctx.Err() != nil {
+            break
+        }
+        rowNum := i + 1
+        if invalid[rowNum] {
+            continue
+        }
+
+        user := &model.User{
+            Email:      row.Email,
+            Username:   row.Username,
+            FirstName:  row.FirstName,
+            LastName:   row.LastName,
+            Attributes: row.Attributes,
+        }
+
+        // Only generated passwords are sent in the invitation; a password
+        // supplied in the file is already known to the user.
+        var generated string
+
+        if row.PasswordHash != "" {
+            // Foreign hashes are stored as-is and upgraded on first login.
+            user.Password = row.PasswordHash
+            user.PasswordAlgo = row.PasswordAlgo
+            if row.PasswordAlgo == utils.HashFirebaseScrypt {
+                user.Password = utils.FormatFirebaseHash(row.PasswordSalt, row.PasswordHash)
+            }
+        } else {
+            password := row.Password
+            if password == "" {
+                var err error
+                generated, err = utils.GenerateRandomPassword(16)
+                if err != nil {
+                    job.Errors = append(job.Errors, model.RowError{Row: rowNum, Email: row.Email, Error: "failed to generate password"})
+                    job.Failed++
+                    job.Processed++
+                    continue
+                }
+                password = generated
+            }
+
+            hashedPassword, err := hashPassword(ctx, password)
+            if err != nil {
+                job.Errors = append(job.Errors, model.RowError{Row: rowNum, Email: row.Email, Error: "failed to hash password"})
+                job.Failed++
+                job.Processed++
+                continue
+            }
+            user.Password = hashedPassword
+        }
+
+        batch = append(batch, pending{
+            row:      rowNum,
+            user:     user,
+            password: generated,
+            invite:   opts.SendInvites,
+        })
+
+        if len(batch) >= batchSize {
+            flush()
+        }
+    }
+    if ctx.Err() != nil {
+        // Record the interruption even though the jobs context is done
+        ctx = context.WithoutCancel(ctx)
+        now := time.Now()
+        job.FinishedAt = &now
+        job.Status = model.JobStatusFailed
+        job.Errors = append(job.Errors, model.RowError{Error: jobInterruptedReason})
+        s.saveJob(ctx, log, job)
+        log.Warn("User import interrupted", "processed", job.Processed, "succeeded", job.Succeeded)
+        return
+    }
+    flush()
+
+    now := time.Now()
+    job.FinishedAt = &now
+    job.Status = model.JobStatusCompleted
+    if job.Succeeded == 0 && job.Total > 0 {
+        job.Status = model.JobStatusFailed
+    }
+    s.saveJob(ctx, log, job)
+    log.Info("User import finished", "succeeded", job.Succeeded, "failed", job.Failed)
+}
+
+func (s *UserImportService) saveJob(ctx context.Context, log logger.Logger, job *model.Job) {
+    if err := s.jobRepo.Update(ctx, job); err != nil {
+        log.Error("Failed to update job", err)
+    }
+}
+
+// createUsers inserts a batch and gives every user the default role, as
+// registering does. Either the whole batch is created or none of it.
+func (s *UserImportService) createUsers(ctx context.Context, users []*model.User) error {
+    return s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+        if err := s.userRepo.CreateBatch(ctx, users); err != nil {
+            return err
+        }
+        for _, user := range users {
+            if err := s.roleRepo.AssignRole(ctx, user.ID, model.RoleUser); err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+}
+
+func (s *UserImportService) sendInvite(ctx context.Context, log logger.Logger, user *model.User, password string) {
+    body := fmt.Sprintf("Hi %s,\n\nAn account has been created for you with the email %s.\n", user.FirstName, user.Email)
+    if password != "" {
+        body += fmt.Sprintf("Your temporary password is: %s\nPlease change it after signing in.\n", password)
+    }
+
+    msg := mailer.Message{
+        To:      user.Email,
+        Subject: "You have been invited",
+        Body:    body,
+    }
+    if err := s.mailer.Send(ctx, msg); err != nil {
+        log.Error("Failed to send invitation", err, "email", user.Email)
+    }
+}
+
+// ImportValidationError is returned by StartImport when rows fail validation
+// and the caller did not ask to skip them.
+type ImportValidationError struct {
+    Report *model.ImportReport
+}
+
+func (e *ImportValidationError) Error() string {
+    return fmt.Sprintf("%d of %d rows failed validation", e.Report.Invalid, e.Report.Total)
+}
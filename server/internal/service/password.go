@@ -0,0 +1,33 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/francis/projectx-api/internal/metrics"
+	"github.com/francis/projectx-api/internal/tracing"
+	"github.com/francis/projectx-api/pkg/utils"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// hashPassword is utils.HashPassword, timed for the password hash metric
+// and traced, since hashing is slow on purpose.
+func hashPassword(ctx context.Context, password string) (string, error) {
+    _, span := tracing.Start(ctx, "password.hash", attribute.String("password.algorithm", utils.HashBcrypt))
+    defer span.End()
+    defer metrics.ObservePasswordHash("hash", utils.HashBcrypt, time.Now())
+    return utils.HashPassword(password)
+}
+
+// checkPassword is utils.CheckPasswordHashWithAlgo, timed and traced like
+// hashPassword.
+func checkPassword(ctx context.Context, algo, password, hash string, params utils.LegacyHashParams) (bool, error) {
+    label := algo
+    if label == "" {
+        label = utils.HashBcrypt
+    }
+    _, span := tracing.Start(ctx, "password.verify", attribute.String("password.algorithm", label))
+    defer span.End()
+    defer metrics.ObservePasswordHash("verify", label, time.Now())
+    return utils.CheckPasswordHashWithAlgo(algo, password, hash, params)
+}
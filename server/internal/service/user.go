@@ -3,35 +3,89 @@ package service
 import (
 	"context"
 	"math"
+	"reflect"
 
+	"github.com/francis/projectx-api/internal/apperror"
 	"github.com/francis/projectx-api/internal/model"
 	"github.com/francis/projectx-api/internal/repository"
+	"github.com/francis/projectx-api/internal/tracing"
+	"github.com/francis/projectx-api/pkg/validator"
 )
 
 type UserService struct {
-    userRepo repository.UserRepository
+    userRepo   repository.UserRepository
+    attributes *AttributeService
 }
 
-func NewUserService(userRepo repository.UserRepository) *UserService {
-    return &UserService{userRepo: userRepo}
+func NewUserService(userRepo repository.UserRepository, attributes *AttributeService) *UserService {
+    return &UserService{
+        userRepo:   userRepo,
+        attributes: attributes,
+    }
 }
 
 func (s *UserService) GetByID(ctx context.Context, id int) (*model.User, error) {
+    ctx, span := tracing.Start(ctx, "UserService.GetByID")
+    defer span.End()
+
     return s.userRepo.GetByID(ctx, id)
 }
 
+// Update saves profile fields. Custom attributes are validated and replaced
+// only when user.Attributes is non-nil. The user's organization is kept as
+// it is: sending it back unchanged is allowed, changing it is not.
 func (s *UserService) Update(ctx context.Context, user *model.User) error {
+    ctx, span := tracing.Start(ctx, "UserService.Update")
+    defer span.End()
+
+    if err := validator.ValidateField("username", user.Username, "omitempty,username"); err != nil {
+        return apperror.Invalid(err)
+    }
+    if user.Attributes != nil {
+        if err := s.keepOrg(ctx, user); err != nil {
+            return err
+        }
+        if err := s.attributes.Validate(ctx, user.Attributes); err != nil {
+            return err
+        }
+    }
     return s.userRepo.Update(ctx, user)
 }
 
-func (s *UserService) GetUsers(ctx context.Context, page, limit int) (*model.PaginatedResponse, error) {
+// keepOrg copies the stored organization into the attributes about to
+// replace it, and rejects a different one.
+func (s *UserService) keepOrg(ctx context.Context, user *model.User) error {
+    current, err := s.userRepo.GetByID(ctx, user.ID)
+    if err != nil {
+        return err
+    }
+    org, hasOrg := current.Attributes[model.OrgAttribute]
+    if sent, ok := user.Attributes[model.OrgAttribute]; ok && (!hasOrg || !reflect.DeepEqual(sent, org)) {
+        return errOrgNotEditable
+    }
+    if hasOrg {
+        user.Attributes[model.OrgAttribute] = org
+    }
+    return nil
+}
+
+// ParseAttributeFilter converts raw attr[key]=value query parameters into a
+// typed filter.
+func (s *UserService) ParseAttributeFilter(ctx context.Context, raw map[string]string) (model.Attributes, error) {
+    return s.attributes.ParseFilter(ctx, raw)
+}
+
+func (s *UserService) GetUsers(ctx context.Context, filter model.UserFilter, page, limit int) (*model.PaginatedResponse, error) {
+    ctx, span := tracing.Start(ctx, "UserService.GetUsers")
+    defer span.End()
+
     offset := (page - 1) * limit
-    users, err := s.userRepo.List(ctx, limit, offset)
+    users, err := s.userRepo.List(ctx, filter, limit, offset)
     if err != nil {
         return nil, err
     }
 
-    total, err := s.userRepo.Count(ctx)
+    total, err := s.userRepo.Count(ctx, filter)
     if err != nil {
         return nil, err
     }
@@ -46,3 +100,36 @@ func (s *UserService) GetUsers(ctx context.Context, page, limit int) (*model.Pag
         TotalPages: totalPages,
     }, nil
 }
+
+// AttributeDefinitions lists the custom attributes users can have.
+func (s *UserService) AttributeDefinitions(ctx context.Context) ([]*model.AttributeDefinition, error) {
+    return s.attributes.List(ctx)
+}
+
+func (s *UserService) Stream(ctx context.Context, filter model.UserFilter, fn func(*model.User) error) error {
+    ctx, span := tracing.Start(ctx, "UserService.Stream")
+    defer span.End()
+
+    return s.userRepo.Stream(ctx, filter, fn)
+}
+
+func (s *UserService) Search(ctx context.Context, query string, page, limit int) (*model.PaginatedResponse, error) {
+    ctx, span := tracing.Start(ctx, "UserService.Search")
+    defer span.End()
+
+    offset := (page - 1) * limit
+    results, total, err := s.userRepo.Search(ctx, query, limit, offset)
+    if err != nil {
+        return nil, err
+    }
+
+    totalPages := int(math.Ceil(float64(total) / float64(limit)))
+
+    return &model.PaginatedResponse{
+        Data:       results,
+        Page:       page,
+        Limit:      limit,
+        Total:      total,
+        TotalPages: totalPages,
+    }, nil
+}
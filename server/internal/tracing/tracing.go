@@ -0,0 +1,122 @@
+// Package tracing sets up OpenTelemetry tracing. Spans are exported with
+// OTLP over HTTP, written to stdout, or, with the "none" exporter, only
+// used to carry incoming W3C trace context through to the logs.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporters accepted in Config.Exporter.
+const (
+    ExporterNone   = "none"
+    ExporterStdout = "stdout"
+    ExporterOTLP   = "otlp"
+)
+
+const instrumentationName = "github.com/francis/projectx-api"
+
+// tracer follows the provider installed by Setup, even though it is
+// created before Setup runs.
+var tracer = otel.Tracer(instrumentationName)
+
+// Config selects the exporter and how many traces are kept. Endpoint is
+// the OTLP collector's host:port; SampleRatio applies to traces that do
+// not arrive with a sampling decision from the caller.
+type Config struct {
+    Exporter       string
+    Endpoint       string
+    Insecure       bool
+    SampleRatio    float64
+    ServiceName    string
+    ServiceVersion string
+    Environment    string
+}
+
+// Setup installs the tracer provider and the W3C trace context and baggage
+// propagators. The returned function flushes buffered spans and must be
+// called on shutdown.
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+    otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+        propagation.TraceContext{},
+        propagation.Baggage{},
+    ))
+
+    var exporter sdktrace.SpanExporter
+    var err error
+    switch cfg.Exporter {
+    case "", ExporterNone:
+        return func(context.Context) error { return nil }, nil
+    case ExporterStdout:
+        exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+    case ExporterOTLP:
+        opts := []otlptracehttp.Option{}
+        if cfg.Endpoint != "" {
+            opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+        }
+        if cfg.Insecure {
+            opts = append(opts, otlptracehttp.WithInsecure())
+        }
+        exporter, err = otlptracehttp.New(ctx, opts...)
+    default:
+        return nil, fmt.Errorf("unknown trace exporter %q", cfg.Exporter)
+    }
+    if err != nil {
+        return nil, fmt.Errorf("failed to create %s trace exporter: %w", cfg.Exporter, err)
+    }
+
+    res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+        semconv.ServiceName(cfg.ServiceName),
+        semconv.ServiceVersion(cfg.ServiceVersion),
+        semconv.DeploymentEnvironment(cfg.Environment),
+    ))
+    if err != nil {
+        return nil, fmt.Errorf("failed to describe trace resource: %w", err)
+    }
+
+    provider := sdktrace.NewTracerProvider(
+        sdktrace.WithBatcher(exporter),
+        sdktrace.WithResource(res),
+        sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+    )
+    otel.SetTracerProvider(provider)
+    return provider.Shutdown, nil
+}
+
+// Start starts a span called name as a child of the span in ctx.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+    return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartServer starts the span for an incoming request, continuing the
+// trace whose context is in ctx.
+func StartServer(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+    return tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...))
+}
+
+// Traced reports whether ctx carries a span, so that work done outside any
+// request, such as migrations and background refreshes, can stay out of
+// the traces.
+func Traced(ctx context.Context) bool {
+    return trace.SpanContextFromContext(ctx).IsValid()
+}
+
+// RecordError marks the span in ctx as failed because of err.
+func RecordError(ctx context.Context, err error) {
+    span := trace.SpanFromContext(ctx)
+    span.RecordError(err)
+    span.SetStatus(codes.Error, err.Error())
+}
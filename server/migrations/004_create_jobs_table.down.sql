@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS update_jobs_updated_at ON jobs;
+DROP INDEX IF EXISTS idx_jobs_created_by;
+DROP INDEX IF EXISTS idx_jobs_type;
+DROP TABLE IF EXISTS jobs;
\ No newline at end of file
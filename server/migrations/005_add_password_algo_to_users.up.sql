@@ -0,0 +1,4 @@
+-- Imported users may carry hashes from other systems (Firebase, Django,
+-- crypt). They are upgraded to bcrypt on the next successful login.
+ALTER TABLE users ALTER COLUMN password_hash TYPE TEXT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_algo VARCHAR(32) NOT NULL DEFAULT 'bcrypt';
@@ -0,0 +1,15 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Names carry more weight than email. The email is indexed both whole and
+-- split on punctuation so "smi" matches "jon.smith@example.com".
+ALTER TABLE users ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', coalesce(first_name, '') || ' ' || coalesce(last_name, '')), 'A') ||
+        setweight(to_tsvector('simple', coalesce(email, '') || ' ' || regexp_replace(coalesce(email, ''), '[@._+-]', ' ', 'g')), 'B')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_users_search_vector ON users USING GIN (search_vector);
+
+-- Trigram index for typo-tolerant matching with the % operator.
+CREATE INDEX IF NOT EXISTS idx_users_search_trgm ON users
+    USING GIN ((first_name || ' ' || last_name || ' ' || email) gin_trgm_ops);
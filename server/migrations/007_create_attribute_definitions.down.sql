@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS update_attribute_definitions_updated_at ON attribute_definitions;
+DROP TABLE IF EXISTS attribute_definitions;
+DROP INDEX IF EXISTS idx_users_attributes;
+ALTER TABLE users DROP COLUMN IF EXISTS attributes;
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS notify_feature_flags_changed ON feature_flags;
+DROP TRIGGER IF EXISTS update_feature_flags_updated_at ON feature_flags;
+DROP FUNCTION IF EXISTS notify_feature_flags_changed();
+DROP TABLE IF EXISTS feature_flags;
\ No newline at end of file
@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS feature_flags (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(64) UNIQUE NOT NULL,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    rules JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TRIGGER update_feature_flags_updated_at
+    BEFORE UPDATE ON feature_flags
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Tell every API instance to refresh its flag cache when a flag changes.
+CREATE OR REPLACE FUNCTION notify_feature_flags_changed()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        PERFORM pg_notify('feature_flags', OLD.name);
+    ELSE
+        PERFORM pg_notify('feature_flags', NEW.name);
+    END IF;
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER notify_feature_flags_changed
+    AFTER INSERT OR UPDATE OR DELETE ON feature_flags
+    FOR EACH ROW
+    EXECUTE FUNCTION notify_feature_flags_changed();
\ No newline at end of file
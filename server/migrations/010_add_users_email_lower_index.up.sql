@@ -0,0 +1,3 @@
+-- Emails are unique regardless of case, like usernames. Addresses that
+-- differ only in case must be merged before this runs.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower ON users (LOWER(email));
@@ -0,0 +1,8 @@
+// Package migrations embeds the SQL migration files, so the API binary can
+// apply them without the migrations directory or the migrate CLI.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,131 @@
+package logger
+
+import (
+    "bytes"
+    "context"
+    "io"
+    "log/slog"
+    "os"
+    "strconv"
+    "strings"
+    "sync"
+)
+
+const (
+    colorReset  = "\033[0m"
+    colorDebug  = "\033[36m"
+    colorWarn   = "\033[33m"
+    colorError  = "\033[31m"
+    colorSubtle = "\033[2m"
+)
+
+// consoleHandler writes one readable line per record, for development:
+//
+//	16:31:18.042 INFO  Request served method=GET status=200
+//
+// Levels are coloured when writing to a terminal.
+type consoleHandler struct {
+    mu     *sync.Mutex
+    w      io.Writer
+    color  bool
+    attrs  string
+    prefix string
+}
+
+func newConsoleHandler(w io.Writer) *consoleHandler {
+    color := false
+    if f, ok := w.(*os.File); ok {
+        if info, err := f.Stat(); err == nil {
+            color = info.Mode()&os.ModeCharDevice != 0
+        }
+    }
+    return &consoleHandler{mu: &sync.Mutex{}, w: w, color: color}
+}
+
+func (h *consoleHandler) Enabled(context.Context, slog.Level) bool {
+    return true
+}
+
+func (h *consoleHandler) Handle(_ context.Context, r slog.Record) error {
+    var buf bytes.Buffer
+    h.paint(&buf, colorSubtle, r.Time.Format("15:04:05.000"))
+    buf.WriteByte(' ')
+    h.paint(&buf, levelColor(r.Level), padLevel(r.Level))
+    buf.WriteByte(' ')
+    buf.WriteString(r.Message)
+    buf.WriteString(h.attrs)
+    r.Attrs(func(a slog.Attr) bool {
+        appendAttr(&buf, h.prefix, a)
+        return true
+    })
+    buf.WriteByte('\n')
+
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    _, err := h.w.Write(buf.Bytes())
+    return err
+}
+
+func (h *consoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+    var buf bytes.Buffer
+    for _, a := range attrs {
+        appendAttr(&buf, h.prefix, a)
+    }
+    next := *h
+    next.attrs += buf.String()
+    return &next
+}
+
+func (h *consoleHandler) WithGroup(name string) slog.Handler {
+    next := *h
+    next.prefix += name + "."
+    return &next
+}
+
+func (h *consoleHandler) paint(buf *bytes.Buffer, color, text string) {
+    if h.color && color != "" {
+        buf.WriteString(color + text + colorReset)
+        return
+    }
+    buf.WriteString(text)
+}
+
+// appendAttr writes a as " key=value", flattening groups into dotted keys.
+func appendAttr(buf *bytes.Buffer, prefix string, a slog.Attr) {
+    a.Value = a.Value.Resolve()
+    if a.Equal(slog.Attr{}) {
+        return
+    }
+    if a.Value.Kind() == slog.KindGroup {
+        if a.Key != "" {
+            prefix += a.Key + "."
+        }
+        for _, ga := range a.Value.Group() {
+            appendAttr(buf, prefix, ga)
+        }
+        return
+    }
+
+    value := a.Value.String()
+    if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+        value = strconv.Quote(value)
+    }
+    buf.WriteString(" " + prefix + a.Key + "=" + value)
+}
+
+func padLevel(level slog.Level) string {
+    s := level.String()
+    return s + strings.Repeat(" ", max(5-len(s), 0))
+}
+
+func levelColor(level slog.Level) string {
+    switch {
+    case level >= slog.LevelError:
+        return colorError
+    case level >= slog.LevelWarn:
+        return colorWarn
+    case level < slog.LevelInfo:
+        return colorDebug
+    }
+    return ""
+}
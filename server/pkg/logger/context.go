@@ -0,0 +1,41 @@
+package logger
+
+import (
+    "context"
+    "sync/atomic"
+)
+
+type ctxKey struct{}
+
+var defaultLogger atomic.Pointer[Logger]
+
+func init() {
+    SetDefault(New("info"))
+}
+
+// SetDefault sets the logger FromContext returns for contexts that carry
+// none, such as those of background work.
+func SetDefault(l Logger) {
+    defaultLogger.Store(&l)
+}
+
+// Default returns the logger set with SetDefault.
+func Default() Logger {
+    return *defaultLogger.Load()
+}
+
+// NewContext returns a copy of ctx that carries l.
+func NewContext(ctx context.Context, l Logger) context.Context {
+    return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext returns the logger carried by ctx, or the default logger. The
+// request middleware stores one that already has the request ID, route and,
+// once authenticated, user ID, so every line logged while serving a request
+// can be tied back to it.
+func FromContext(ctx context.Context) Logger {
+    if l, ok := ctx.Value(ctxKey{}).(Logger); ok {
+        return l
+    }
+    return Default()
+}
@@ -0,0 +1,257 @@
+package logger
+
+import (
+    "context"
+    "log/slog"
+    "math"
+    "strconv"
+    "sync"
+    "sync/atomic"
+    "time"
+)
+
+// Override changes the level for a limited time. Module and UserID narrow
+// it to loggers that carry that "module" or "user_id" attribute; with
+// neither it applies to every logger.
+type Override struct {
+    Level     string
+    Module    string
+    UserID    int
+    ExpiresAt time.Time
+}
+
+func (o Override) matches(module, userID string) bool {
+    return (o.Module == "" || o.Module == module) &&
+        (o.UserID == 0 || strconv.Itoa(o.UserID) == userID)
+}
+
+// Sampling limits how many lines with the same level and message are
+// written per period: the first First lines, then every Thereafter-th.
+// Errors and debug lines are never sampled. First of zero turns sampling
+// off.
+type Sampling struct {
+    First      int
+    Thereafter int
+    Period     time.Duration
+}
+
+// Control holds the levels and sampling of the loggers made with
+// NewWithControl, all of which can be changed while the process runs.
+type Control struct {
+    base      atomic.Int64
+    floor     atomic.Int64
+    overrides atomic.Pointer[[]Override]
+    mu        sync.Mutex
+
+    sampling  atomic.Pointer[Sampling]
+    samplesMu sync.Mutex
+    samples   map[sampleKey]*sample
+}
+
+type sampleKey struct {
+    level slog.Level
+    msg   string
+}
+
+type sample struct {
+    start   time.Time
+    count   int
+    dropped int
+}
+
+// maxSampleKeys bounds the sampler's memory when messages are built from
+// variable data.
+const maxSampleKeys = 4096
+
+func NewControl(level slog.Level) *Control {
+    c := &Control{samples: make(map[sampleKey]*sample)}
+    c.base.Store(int64(level))
+    c.overrides.Store(&[]Override{})
+    c.sampling.Store(&Sampling{})
+    c.updateFloor()
+    return c
+}
+
+// SetLevel sets the level used where no override applies.
+func (c *Control) SetLevel(level slog.Level) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    c.base.Store(int64(level))
+    c.updateFloor()
+}
+
+// Level returns the level used where no override applies.
+func (c *Control) Level() slog.Level {
+    return slog.Level(c.base.Load())
+}
+
+// SetOverride adds o, replacing any override with the same scope.
+func (c *Control) SetOverride(o Override) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    next := []Override{o}
+    for _, existing := range c.active() {
+        if existing.Module != o.Module || existing.UserID != o.UserID {
+            next = append(next, existing)
+        }
+    }
+    c.overrides.Store(&next)
+    c.updateFloor()
+}
+
+// ClearOverrides removes every override, restoring the base level.
+func (c *Control) ClearOverrides() {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    c.overrides.Store(&[]Override{})
+    c.updateFloor()
+}
+
+// Overrides returns the overrides that have not expired.
+func (c *Control) Overrides() []Override {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    active := c.active()
+    c.overrides.Store(&active)
+    c.updateFloor()
+    return append([]Override(nil), active...)
+}
+
+// SetSampling replaces the sampling settings.
+func (c *Control) SetSampling(s Sampling) {
+    c.sampling.Store(&s)
+    c.samplesMu.Lock()
+    c.samples = make(map[sampleKey]*sample)
+    c.samplesMu.Unlock()
+}
+
+// active returns the overrides that have not expired. c.mu must be held.
+func (c *Control) active() []Override {
+    now := time.Now()
+    var active []Override
+    for _, o := range *c.overrides.Load() {
+        if now.Before(o.ExpiresAt) {
+            active = append(active, o)
+        }
+    }
+    return active
+}
+
+// updateFloor records the lowest level any logger may log at, so most
+// disabled calls are rejected without looking at the overrides. c.mu must
+// be held.
+func (c *Control) updateFloor() {
+    floor := c.base.Load()
+    for _, o := range *c.overrides.Load() {
+        floor = min(floor, int64(ParseLevel(o.Level)))
+    }
+    c.floor.Store(floor)
+}
+
+// enabled reports whether a logger with the given module and user ID logs
+// at level. When overrides match, the lowest of their levels applies
+// instead of the base level, so an unscoped override can also quieten
+// logging.
+func (c *Control) enabled(level slog.Level, module, userID string) bool {
+    if int64(level) < c.floor.Load() {
+        return false
+    }
+
+    now := time.Now()
+    threshold, matched, expired := slog.Level(math.MaxInt), false, false
+    for _, o := range *c.overrides.Load() {
+        if !now.Before(o.ExpiresAt) {
+            expired = true
+            continue
+        }
+        if o.matches(module, userID) {
+            threshold = min(threshold, ParseLevel(o.Level))
+            matched = true
+        }
+    }
+    if expired {
+        c.Overrides()
+    }
+    if !matched {
+        threshold = c.Level()
+    }
+    return level >= threshold
+}
+
+// sample reports whether a line should be written and how many like it
+// were dropped since the last one that was.
+func (c *Control) sample(r slog.Record) (keep bool, dropped int) {
+    s := c.sampling.Load()
+    if s.First <= 0 || r.Level < slog.LevelInfo || r.Level >= slog.LevelError {
+        return true, 0
+    }
+
+    c.samplesMu.Lock()
+    defer c.samplesMu.Unlock()
+
+    key := sampleKey{r.Level, r.Message}
+    st, ok := c.samples[key]
+    if !ok || r.Time.Sub(st.start) >= s.Period {
+        if len(c.samples) >= maxSampleKeys {
+            c.samples = make(map[sampleKey]*sample)
+        }
+        if ok {
+            dropped = st.dropped
+        }
+        st = &sample{start: r.Time}
+        c.samples[key] = st
+    }
+
+    st.count++
+    if st.count <= s.First || (s.Thereafter > 0 && (st.count-s.First)%s.Thereafter == 0) {
+        dropped, st.dropped = dropped+st.dropped, 0
+        return true, dropped
+    }
+    st.dropped++
+    return false, 0
+}
+
+// controlHandler applies a Control to the records passed to the wrapped
+// handler. It follows the "module" and "user_id" attributes added with
+// With, which is how overrides find the loggers they apply to.
+type controlHandler struct {
+    inner  slog.Handler
+    ctl    *Control
+    module string
+    userID string
+}
+
+func (h *controlHandler) Enabled(_ context.Context, level slog.Level) bool {
+    return h.ctl.enabled(level, h.module, h.userID)
+}
+
+func (h *controlHandler) Handle(ctx context.Context, r slog.Record) error {
+    keep, dropped := h.ctl.sample(r)
+    if !keep {
+        return nil
+    }
+    if dropped > 0 {
+        r.AddAttrs(slog.Int("sampled_out", dropped))
+    }
+    return h.inner.Handle(ctx, r)
+}
+
+func (h *controlHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+    next := *h
+    next.inner = h.inner.WithAttrs(attrs)
+    for _, a := range attrs {
+        switch a.Key {
+        case "module":
+            next.module = a.Value.String()
+        case "user_id":
+            next.userID = a.Value.String()
+        }
+    }
+    return &next
+}
+
+func (h *controlHandler) WithGroup(name string) slog.Handler {
+    next := *h
+    next.inner = h.inner.WithGroup(name)
+    return &next
+}
@@ -0,0 +1,266 @@
+package logger
+
+import (
+    "bytes"
+    "encoding/json"
+    "log/slog"
+    "math"
+    "testing"
+    "time"
+)
+
+func TestControlEnabled(t *testing.T) {
+    future := time.Now().Add(time.Hour)
+    past := time.Now().Add(-time.Second)
+
+    tests := []struct {
+        name      string
+        base      slog.Level
+        overrides []Override
+        level     slog.Level
+        module    string
+        userID    string
+        want      bool
+    }{
+        {name: "base level", base: slog.LevelInfo, level: slog.LevelInfo, want: true},
+        {name: "below base level", base: slog.LevelInfo, level: slog.LevelDebug},
+        {
+            name:      "global override",
+            base:      slog.LevelInfo,
+            overrides: []Override{{Level: "debug", ExpiresAt: future}},
+            level:     slog.LevelDebug,
+            want:      true,
+        },
+        {
+            name:      "global override quietens",
+            base:      slog.LevelInfo,
+            overrides: []Override{{Level: "error", ExpiresAt: future}},
+            level:     slog.LevelWarn,
+        },
+        {
+            name:      "module override matches",
+            base:      slog.LevelInfo,
+            overrides: []Override{{Level: "debug", Module: "auth", ExpiresAt: future}},
+            level:     slog.LevelDebug,
+            module:    "auth",
+            want:      true,
+        },
+        {
+            name:      "module override other module",
+            base:      slog.LevelInfo,
+            overrides: []Override{{Level: "debug", Module: "auth", ExpiresAt: future}},
+            level:     slog.LevelDebug,
+            module:    "import",
+        },
+        {
+            name:      "user override matches",
+            base:      slog.LevelWarn,
+            overrides: []Override{{Level: "debug", UserID: 42, ExpiresAt: future}},
+            level:     slog.LevelInfo,
+            userID:    "42",
+            want:      true,
+        },
+        {
+            name:      "user override other user",
+            base:      slog.LevelWarn,
+            overrides: []Override{{Level: "debug", UserID: 42, ExpiresAt: future}},
+            level:     slog.LevelInfo,
+            userID:    "7",
+        },
+        {
+            name:      "module and user both needed",
+            base:      slog.LevelInfo,
+            overrides: []Override{{Level: "debug", Module: "auth", UserID: 42, ExpiresAt: future}},
+            level:     slog.LevelDebug,
+            module:    "auth",
+            userID:    "7",
+        },
+        {
+            name: "lowest matching override wins",
+            base: slog.LevelInfo,
+            overrides: []Override{
+                {Level: "error", ExpiresAt: future},
+                {Level: "debug", Module: "auth", ExpiresAt: future},
+            },
+            level:  slog.LevelDebug,
+            module: "auth",
+            want:   true,
+        },
+        {
+            name:      "expired override",
+            base:      slog.LevelInfo,
+            overrides: []Override{{Level: "debug", ExpiresAt: past}},
+            level:     slog.LevelDebug,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            ctl := NewControl(tt.base)
+            for _, o := range tt.overrides {
+                ctl.SetOverride(o)
+            }
+            if got := ctl.enabled(tt.level, tt.module, tt.userID); got != tt.want {
+                t.Errorf("enabled(%s, %q, %q) = %v, want %v", tt.level, tt.module, tt.userID, got, tt.want)
+            }
+        })
+    }
+}
+
+func TestControlOverrides(t *testing.T) {
+    ctl := NewControl(slog.LevelInfo)
+    future := time.Now().Add(time.Hour)
+
+    ctl.SetOverride(Override{Level: "debug", Module: "auth", ExpiresAt: future})
+    ctl.SetOverride(Override{Level: "warn", Module: "auth", ExpiresAt: future})
+    ctl.SetOverride(Override{Level: "debug", UserID: 42, ExpiresAt: future})
+    ctl.SetOverride(Override{Level: "debug", ExpiresAt: time.Now().Add(-time.Second)})
+
+    got := ctl.Overrides()
+    if len(got) != 2 {
+        t.Fatalf("Overrides() = %+v, want the auth and user overrides", got)
+    }
+    for _, o := range got {
+        if o.Module == "auth" && o.Level != "warn" {
+            t.Errorf("auth override level = %q, want the later warn to replace debug", o.Level)
+        }
+    }
+
+    ctl.ClearOverrides()
+    if got := ctl.Overrides(); len(got) != 0 {
+        t.Errorf("Overrides() after ClearOverrides = %+v, want none", got)
+    }
+    if ctl.enabled(slog.LevelDebug, "", "42") {
+        t.Error("debug enabled after ClearOverrides, want the base level back")
+    }
+}
+
+func TestControlSample(t *testing.T) {
+    start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+    record := func(level slog.Level, msg string, after time.Duration) slog.Record {
+        return slog.NewRecord(start.Add(after), level, msg, 0)
+    }
+
+    type step struct {
+        record      slog.Record
+        wantKeep    bool
+        wantDropped int
+    }
+    tests := []struct {
+        name     string
+        sampling Sampling
+        steps    []step
+    }{
+        {
+            name:     "off",
+            sampling: Sampling{},
+            steps: []step{
+                {record(slog.LevelInfo, "hit", 0), true, 0},
+                {record(slog.LevelInfo, "hit", 0), true, 0},
+            },
+        },
+        {
+            name:     "first then every thereafter",
+            sampling: Sampling{First: 2, Thereafter: 3, Period: time.Minute},
+            steps: []step{
+                {record(slog.LevelInfo, "hit", 0), true, 0},
+                {record(slog.LevelInfo, "hit", 0), true, 0},
+                {record(slog.LevelInfo, "hit", 0), false, 0},
+                {record(slog.LevelInfo, "hit", 0), false, 0},
+                {record(slog.LevelInfo, "hit", 0), true, 2},
+                {record(slog.LevelInfo, "hit", 0), false, 0},
+            },
+        },
+        {
+            name:     "first only",
+            sampling: Sampling{First: 1, Period: time.Minute},
+            steps: []step{
+                {record(slog.LevelWarn, "hit", 0), true, 0},
+                {record(slog.LevelWarn, "hit", time.Second), false, 0},
+                {record(slog.LevelWarn, "hit", 2 * time.Second), false, 0},
+                // A new period reports what the last one dropped
+                {record(slog.LevelWarn, "hit", time.Minute), true, 2},
+            },
+        },
+        {
+            name:     "counted per level and message",
+            sampling: Sampling{First: 1, Period: time.Minute},
+            steps: []step{
+                {record(slog.LevelInfo, "hit", 0), true, 0},
+                {record(slog.LevelInfo, "miss", 0), true, 0},
+                {record(slog.LevelWarn, "hit", 0), true, 0},
+                {record(slog.LevelInfo, "hit", 0), false, 0},
+            },
+        },
+        {
+            name:     "errors and debug never sampled",
+            sampling: Sampling{First: 1, Period: time.Minute},
+            steps: []step{
+                {record(slog.LevelError, "hit", 0), true, 0},
+                {record(slog.LevelError, "hit", 0), true, 0},
+                {record(slog.LevelDebug, "hit", 0), true, 0},
+                {record(slog.LevelDebug, "hit", 0), true, 0},
+            },
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            ctl := NewControl(slog.LevelDebug)
+            ctl.SetSampling(tt.sampling)
+            for i, s := range tt.steps {
+                keep, dropped := ctl.sample(s.record)
+                if keep != s.wantKeep || dropped != s.wantDropped {
+                    t.Errorf("step %d: sample() = %v, %d, want %v, %d", i, keep, dropped, s.wantKeep, s.wantDropped)
+                }
+            }
+        })
+    }
+}
+
+func TestLoggerFollowsControl(t *testing.T) {
+    var buf bytes.Buffer
+    ctl := NewControl(slog.LevelInfo)
+    ctl.SetSampling(Sampling{First: 1, Thereafter: 2, Period: time.Hour})
+    log := NewWithSinks(ctl, Sinks{{handler: newJSONHandler(&buf), level: slog.Level(math.MinInt)}})
+    auth := log.With("module", "auth")
+
+    log.Debug("hidden")
+    ctl.SetOverride(Override{Level: "debug", Module: "auth", ExpiresAt: time.Now().Add(time.Hour)})
+    auth.Debug("auth debug")
+    log.Debug("hidden")
+    for i := 0; i < 3; i++ {
+        log.Info("repeated")
+    }
+
+    var lines []map[string]interface{}
+    dec := json.NewDecoder(&buf)
+    for dec.More() {
+        var line map[string]interface{}
+        if err := dec.Decode(&line); err != nil {
+            t.Fatalf("decode log line: %v", err)
+        }
+        lines = append(lines, line)
+    }
+
+    want := []struct {
+        msg     string
+        sampled float64
+    }{
+        {"auth debug", 0},
+        {"repeated", 0},
+        {"repeated", 1},
+    }
+    if len(lines) != len(want) {
+        t.Fatalf("logged %d lines, want %d: %v", len(lines), len(want), lines)
+    }
+    for i, w := range want {
+        if lines[i]["msg"] != w.msg {
+            t.Errorf("line %d msg = %v, want %q", i, lines[i]["msg"], w.msg)
+        }
+        sampled, _ := lines[i]["sampled_out"].(float64)
+        if sampled != w.sampled {
+            t.Errorf("line %d sampled_out = %v, want %v", i, sampled, w.sampled)
+        }
+    }
+}
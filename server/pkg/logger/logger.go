@@ -2,8 +2,8 @@ package logger
 
 import (
     "log/slog"
+    "math"
     "os"
-    "time"
 )
 
 type Logger interface {
@@ -20,31 +20,35 @@ type slogger struct {
 }
 
 func New(level string) Logger {
-    var logLevel slog.Level
+    return NewWithControl(NewControl(ParseLevel(level)))
+}
+
+// NewWithControl returns a logger that writes JSON to stdout, with levels
+// and sampling that follow ctl so they can be changed while the process
+// runs.
+func NewWithControl(ctl *Control) Logger {
+    return NewWithSinks(ctl, Sinks{{handler: newJSONHandler(os.Stdout), level: slog.Level(math.MinInt)}})
+}
+
+// NewWithSinks is NewWithControl writing to sinks instead of stdout.
+func NewWithSinks(ctl *Control, sinks Sinks) Logger {
+    return &slogger{
+        logger: slog.New(&controlHandler{inner: &fanoutHandler{sinks: sinks}, ctl: ctl}),
+    }
+}
+
+// ParseLevel converts a configured level name to a slog level. Unknown names
+// mean info.
+func ParseLevel(level string) slog.Level {
     switch level {
     case "debug":
-        logLevel = slog.LevelDebug
+        return slog.LevelDebug
     case "warn":
-        logLevel = slog.LevelWarn
+        return slog.LevelWarn
     case "error":
-        logLevel = slog.LevelError
+        return slog.LevelError
     default:
-        logLevel = slog.LevelInfo
-    }
-
-    handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-        Level: logLevel,
-        AddSource: true,
-        ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
-            if a.Key == slog.TimeKey {
-                a.Value = slog.StringValue(time.Now().Format(time.RFC3339))
-            }
-            return a
-        },
-    })
-
-    return &slogger{
-        logger: slog.New(handler),
+        return slog.LevelInfo
     }
 }
 
@@ -0,0 +1,169 @@
+package logger
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "io"
+    "log/slog"
+    "math"
+    "os"
+    "time"
+
+    "gopkg.in/natefinch/lumberjack.v2"
+)
+
+// SinkConfig describes one destination for log lines. Type is stdout,
+// stderr, file or syslog and Format json or console. Level, when set, is
+// the lowest level the sink takes. The remaining fields apply to the file
+// and syslog types.
+type SinkConfig struct {
+    Type   string
+    Format string
+    Level  string
+
+    Path       string
+    MaxSizeMB  int
+    MaxAge     time.Duration
+    MaxBackups int
+    Compress   bool
+
+    Network  string
+    Address  string
+    Facility string
+    AppName  string
+}
+
+// Sink is an open log destination.
+type Sink struct {
+    handler slog.Handler
+    level   slog.Level
+    closer  io.Closer
+}
+
+// Sinks are the destinations of a logger made with NewWithSinks.
+type Sinks []Sink
+
+// OpenSinks opens every configured sink. If one fails, those already open
+// are closed again.
+func OpenSinks(configs []SinkConfig) (Sinks, error) {
+    var sinks Sinks
+    for i, cfg := range configs {
+        sink, err := openSink(cfg)
+        if err != nil {
+            sinks.Close()
+            return nil, fmt.Errorf("log sink %d (%s): %w", i, cfg.Type, err)
+        }
+        sinks = append(sinks, sink)
+    }
+    return sinks, nil
+}
+
+// Close closes the sinks that hold files or connections.
+func (s Sinks) Close() error {
+    var errs []error
+    for _, sink := range s {
+        if sink.closer != nil {
+            errs = append(errs, sink.closer.Close())
+        }
+    }
+    return errors.Join(errs...)
+}
+
+func openSink(cfg SinkConfig) (Sink, error) {
+    sink := Sink{level: slog.Level(math.MinInt)}
+    if cfg.Level != "" {
+        sink.level = ParseLevel(cfg.Level)
+    }
+
+    var w io.Writer
+    switch cfg.Type {
+    case "stdout":
+        w = os.Stdout
+    case "stderr":
+        w = os.Stderr
+    case "file":
+        file := &lumberjack.Logger{
+            Filename: cfg.Path,
+            MaxSize:  cfg.MaxSizeMB,
+            // lumberjack counts whole days
+            MaxAge:     int(math.Ceil(cfg.MaxAge.Hours() / 24)),
+            MaxBackups: cfg.MaxBackups,
+            LocalTime:  true,
+            Compress:   cfg.Compress,
+        }
+        w, sink.closer = file, file
+    case "syslog":
+        sw, err := dialSyslog(cfg)
+        if err != nil {
+            return Sink{}, err
+        }
+        w, sink.closer = sw, sw
+    default:
+        return Sink{}, fmt.Errorf("unknown sink type %q", cfg.Type)
+    }
+
+    if cfg.Format == "console" {
+        sink.handler = newConsoleHandler(w)
+    } else {
+        sink.handler = newJSONHandler(w)
+    }
+    if sw, ok := w.(*syslogWriter); ok {
+        sink.handler = &syslogHandler{inner: sink.handler, w: sw}
+    }
+    return sink, nil
+}
+
+func newJSONHandler(w io.Writer) slog.Handler {
+    return slog.NewJSONHandler(w, &slog.HandlerOptions{
+        // The Control decides what is logged
+        Level:     slog.Level(math.MinInt),
+        AddSource: true,
+        ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+            if a.Key == slog.TimeKey {
+                a.Value = slog.StringValue(time.Now().Format(time.RFC3339))
+            }
+            return a
+        },
+    })
+}
+
+// fanoutHandler sends each record to every sink whose level it reaches.
+type fanoutHandler struct {
+    sinks []Sink
+}
+
+func (h *fanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+    for _, s := range h.sinks {
+        if level >= s.level {
+            return true
+        }
+    }
+    return false
+}
+
+func (h *fanoutHandler) Handle(ctx context.Context, r slog.Record) error {
+    var errs []error
+    for _, s := range h.sinks {
+        if r.Level >= s.level {
+            errs = append(errs, s.handler.Handle(ctx, r.Clone()))
+        }
+    }
+    return errors.Join(errs...)
+}
+
+func (h *fanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+    return h.with(func(inner slog.Handler) slog.Handler { return inner.WithAttrs(attrs) })
+}
+
+func (h *fanoutHandler) WithGroup(name string) slog.Handler {
+    return h.with(func(inner slog.Handler) slog.Handler { return inner.WithGroup(name) })
+}
+
+func (h *fanoutHandler) with(fn func(slog.Handler) slog.Handler) slog.Handler {
+    next := &fanoutHandler{sinks: make([]Sink, len(h.sinks))}
+    for i, s := range h.sinks {
+        next.sinks[i] = Sink{handler: fn(s.handler), level: s.level}
+    }
+    return next
+}
@@ -0,0 +1,183 @@
+package logger
+
+import (
+    "bytes"
+    "context"
+    "fmt"
+    "log/slog"
+    "net"
+    "os"
+    "path/filepath"
+    "strconv"
+    "sync"
+    "time"
+)
+
+var syslogFacilities = map[string]int{
+    "kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5,
+    "lpr": 6, "news": 7, "uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+    "local0": 16, "local1": 17, "local2": 18, "local3": 19,
+    "local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// localSyslogSockets are where the local daemon listens on common systems.
+var localSyslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// syslogWriter sends each write as one RFC 5424 message. Over TCP messages
+// are framed by octet counting (RFC 6587). A broken connection is dialled
+// again on the next write.
+type syslogWriter struct {
+    mu       sync.Mutex
+    network  string
+    address  string
+    conn     net.Conn
+    facility int
+    hostname string
+    appName  string
+    procID   string
+
+    // Set by syslogHandler for the record being written
+    severity int
+    time     time.Time
+}
+
+func dialSyslog(cfg SinkConfig) (*syslogWriter, error) {
+    facility := syslogFacilities["local0"]
+    if cfg.Facility != "" {
+        f, ok := syslogFacilities[cfg.Facility]
+        if !ok {
+            return nil, fmt.Errorf("unknown syslog facility %q", cfg.Facility)
+        }
+        facility = f
+    }
+
+    hostname, _ := os.Hostname()
+    appName := cfg.AppName
+    if appName == "" {
+        appName = filepath.Base(os.Args[0])
+    }
+
+    w := &syslogWriter{
+        network:  cfg.Network,
+        address:  cfg.Address,
+        facility: facility,
+        hostname: nilValue(hostname),
+        appName:  nilValue(appName),
+        procID:   strconv.Itoa(os.Getpid()),
+    }
+    if err := w.connect(); err != nil {
+        return nil, err
+    }
+    return w, nil
+}
+
+func (w *syslogWriter) connect() error {
+    if w.network != "" {
+        conn, err := net.DialTimeout(w.network, w.address, 5*time.Second)
+        if err != nil {
+            return err
+        }
+        w.conn = conn
+        return nil
+    }
+
+    for _, path := range localSyslogSockets {
+        for _, network := range []string{"unixgram", "unix"} {
+            if conn, err := net.Dial(network, path); err == nil {
+                w.conn = conn
+                return nil
+            }
+        }
+    }
+    return fmt.Errorf("no local syslog daemon found")
+}
+
+// Write sends p, a formatted log line, with the syslog header for the
+// record set by syslogHandler.
+func (w *syslogWriter) Write(p []byte) (int, error) {
+    msg := w.format(bytes.TrimRight(p, "\n"))
+    if w.conn == nil {
+        if err := w.connect(); err != nil {
+            return 0, err
+        }
+    }
+    if _, err := w.conn.Write(msg); err != nil {
+        w.conn.Close()
+        w.conn = nil
+        return 0, err
+    }
+    return len(p), nil
+}
+
+// format builds "<PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID SD MSG".
+func (w *syslogWriter) format(p []byte) []byte {
+    var buf bytes.Buffer
+    fmt.Fprintf(&buf, "<%d>1 %s %s %s %s - - ",
+        w.facility*8+w.severity,
+        w.time.Format("2006-01-02T15:04:05.000000Z07:00"),
+        w.hostname,
+        w.appName,
+        w.procID,
+    )
+    buf.Write(p)
+    if w.network == "tcp" {
+        return append([]byte(strconv.Itoa(buf.Len())+" "), buf.Bytes()...)
+    }
+    return buf.Bytes()
+}
+
+func (w *syslogWriter) Close() error {
+    w.mu.Lock()
+    defer w.mu.Unlock()
+    if w.conn == nil {
+        return nil
+    }
+    return w.conn.Close()
+}
+
+// syslogHandler tells the writer the severity and time of each record
+// before the wrapped handler formats it.
+type syslogHandler struct {
+    inner slog.Handler
+    w     *syslogWriter
+}
+
+func (h *syslogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+    return h.inner.Enabled(ctx, level)
+}
+
+func (h *syslogHandler) Handle(ctx context.Context, r slog.Record) error {
+    h.w.mu.Lock()
+    defer h.w.mu.Unlock()
+    h.w.severity = syslogSeverity(r.Level)
+    h.w.time = r.Time
+    return h.inner.Handle(ctx, r)
+}
+
+func (h *syslogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+    return &syslogHandler{inner: h.inner.WithAttrs(attrs), w: h.w}
+}
+
+func (h *syslogHandler) WithGroup(name string) slog.Handler {
+    return &syslogHandler{inner: h.inner.WithGroup(name), w: h.w}
+}
+
+func syslogSeverity(level slog.Level) int {
+    switch {
+    case level >= slog.LevelError:
+        return 3
+    case level >= slog.LevelWarn:
+        return 4
+    case level >= slog.LevelInfo:
+        return 6
+    }
+    return 7
+}
+
+// nilValue returns s, or the RFC 5424 NILVALUE when s is empty.
+func nilValue(s string) string {
+    if s == "" {
+        return "-"
+    }
+    return s
+}
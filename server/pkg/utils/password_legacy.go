@@ -0,0 +1,292 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Password hash algorithms a user record can be tagged with. Users imported
+// from other systems keep their original hash until their next successful
+// login, when it is replaced with a native bcrypt hash.
+const (
+    HashBcrypt         = "bcrypt"
+    HashFirebaseScrypt = "firebase_scrypt"
+    HashDjangoPBKDF2   = "django_pbkdf2"
+    HashSHA512Crypt    = "sha512_crypt"
+)
+
+// FirebaseScryptParams are the project-wide hash parameters shown in the
+// Firebase console under Authentication > Users > Password hash parameters.
+// SignerKey and SaltSeparator are base64 encoded, as exported by Firebase.
+type FirebaseScryptParams struct {
+    SignerKey     string
+    SaltSeparator string
+    Rounds        int
+    MemCost       int
+}
+
+type LegacyHashParams struct {
+    Firebase FirebaseScryptParams
+}
+
+// IsSupportedHashAlgo reports whether algo names a known hash algorithm.
+func IsSupportedHashAlgo(algo string) bool {
+    switch algo {
+    case HashBcrypt, HashFirebaseScrypt, HashDjangoPBKDF2, HashSHA512Crypt:
+        return true
+    }
+    return false
+}
+
+// FormatFirebaseHash combines the per-user salt and hash from a Firebase
+// export into the single value stored in password_hash.
+func FormatFirebaseHash(salt, hash string) string {
+    return salt + "$" + hash
+}
+
+// CheckPasswordHashWithAlgo compares a password against a hash produced by
+// the given algorithm. An error is returned for malformed hashes or missing
+// parameters, never for a simple mismatch.
+func CheckPasswordHashWithAlgo(algo, password, hash string, params LegacyHashParams) (bool, error) {
+    switch algo {
+    case "", HashBcrypt:
+        return CheckPasswordHash(password, hash), nil
+    case HashFirebaseScrypt:
+        return checkFirebaseScrypt(password, hash, params.Firebase)
+    case HashDjangoPBKDF2:
+        return checkDjangoPBKDF2(password, hash)
+    case HashSHA512Crypt:
+        return checkSHA512Crypt(password, hash)
+    default:
+        return false, fmt.Errorf("unsupported password hash algorithm %q", algo)
+    }
+}
+
+// checkFirebaseScrypt implements Firebase's modified scrypt: the scrypt key
+// derived from the password and salt is used to AES-256-CTR encrypt the
+// project signer key, and the ciphertext is the stored hash.
+func checkFirebaseScrypt(password, stored string, params FirebaseScryptParams) (bool, error) {
+    if params.SignerKey == "" {
+        return false, fmt.Errorf("firebase signer key is not configured")
+    }
+
+    saltB64, hashB64, ok := strings.Cut(stored, "$")
+    if !ok {
+        return false, fmt.Errorf("malformed firebase hash")
+    }
+
+    salt, err := base64.StdEncoding.DecodeString(saltB64)
+    if err != nil {
+        return false, fmt.Errorf("malformed firebase salt: %w", err)
+    }
+    expected, err := base64.StdEncoding.DecodeString(hashB64)
+    if err != nil {
+        return false, fmt.Errorf("malformed firebase hash: %w", err)
+    }
+    signerKey, err := base64.StdEncoding.DecodeString(params.SignerKey)
+    if err != nil {
+        return false, fmt.Errorf("malformed firebase signer key: %w", err)
+    }
+    saltSeparator, err := base64.StdEncoding.DecodeString(params.SaltSeparator)
+    if err != nil {
+        return false, fmt.Errorf("malformed firebase salt separator: %w", err)
+    }
+
+    key, err := scrypt.Key([]byte(password), append(salt, saltSeparator...), 1<<params.MemCost, params.Rounds, 1, 32)
+    if err != nil {
+        return false, err
+    }
+
+    block, err := aes.NewCipher(key)
+    if err != nil {
+        return false, err
+    }
+    actual := make([]byte, len(signerKey))
+    cipher.NewCTR(block, make([]byte, aes.BlockSize)).XORKeyStream(actual, signerKey)
+
+    return subtle.ConstantTimeCompare(actual, expected) == 1, nil
+}
+
+// checkDjangoPBKDF2 verifies Django's "<algorithm>$<iterations>$<salt>$<hash>"
+// format for the pbkdf2_sha256 and pbkdf2_sha1 hashers.
+func checkDjangoPBKDF2(password, stored string) (bool, error) {
+    parts := strings.Split(stored, "$")
+    if len(parts) != 4 {
+        return false, fmt.Errorf("malformed django hash")
+    }
+
+    var digest func() hash.Hash
+    switch parts[0] {
+    case "pbkdf2_sha256":
+        digest = sha256.New
+    case "pbkdf2_sha1":
+        digest = sha1.New
+    default:
+        return false, fmt.Errorf("unsupported django hasher %q", parts[0])
+    }
+
+    iterations, err := strconv.Atoi(parts[1])
+    if err != nil || iterations < 1 {
+        return false, fmt.Errorf("malformed django iteration count")
+    }
+    expected, err := base64.StdEncoding.DecodeString(parts[3])
+    if err != nil {
+        return false, fmt.Errorf("malformed django hash: %w", err)
+    }
+
+    actual := pbkdf2.Key([]byte(password), []byte(parts[2]), iterations, len(expected), digest)
+    return subtle.ConstantTimeCompare(actual, expected) == 1, nil
+}
+
+const (
+    sha512CryptDefaultRounds = 5000
+    sha512CryptMinRounds     = 1000
+    sha512CryptMaxRounds     = 999999999
+    cryptAlphabet            = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+// checkSHA512Crypt verifies "$6$[rounds=N$]salt$hash" hashes as produced by
+// glibc crypt(3) and found in /etc/shadow or Passlib exports.
+func checkSHA512Crypt(password, stored string) (bool, error) {
+    if !strings.HasPrefix(stored, "$6$") {
+        return false, fmt.Errorf("malformed sha512-crypt hash")
+    }
+    rest := stored[len("$6$"):]
+
+    rounds := sha512CryptDefaultRounds
+    customRounds := false
+    if strings.HasPrefix(rest, "rounds=") {
+        value, remainder, ok := strings.Cut(rest[len("rounds="):], "$")
+        if !ok {
+            return false, fmt.Errorf("malformed sha512-crypt rounds")
+        }
+        n, err := strconv.Atoi(value)
+        if err != nil {
+            return false, fmt.Errorf("malformed sha512-crypt rounds")
+        }
+        rounds = min(max(n, sha512CryptMinRounds), sha512CryptMaxRounds)
+        customRounds = true
+        rest = remainder
+    }
+
+    salt, _, ok := strings.Cut(rest, "$")
+    if !ok {
+        return false, fmt.Errorf("malformed sha512-crypt hash")
+    }
+    if len(salt) > 16 {
+        salt = salt[:16]
+    }
+
+    actual := sha512Crypt([]byte(password), []byte(salt), rounds, customRounds)
+    return subtle.ConstantTimeCompare([]byte(actual), []byte(stored)) == 1, nil
+}
+
+// sha512Crypt follows Ulrich Drepper's "Unix crypt using SHA-256 and
+// SHA-512" specification step by step.
+func sha512Crypt(password, salt []byte, rounds int, customRounds bool) string {
+    digestB := sha512.New()
+    digestB.Write(password)
+    digestB.Write(salt)
+    digestB.Write(password)
+    b := digestB.Sum(nil)
+
+    digestA := sha512.New()
+    digestA.Write(password)
+    digestA.Write(salt)
+    n := len(password)
+    for ; n > 64; n -= 64 {
+        digestA.Write(b)
+    }
+    digestA.Write(b[:n])
+    for n := len(password); n > 0; n >>= 1 {
+        if n&1 != 0 {
+            digestA.Write(b)
+        } else {
+            digestA.Write(password)
+        }
+    }
+    a := digestA.Sum(nil)
+
+    digestDP := sha512.New()
+    for range password {
+        digestDP.Write(password)
+    }
+    p := repeatTo(digestDP.Sum(nil), len(password))
+
+    digestDS := sha512.New()
+    for i := 0; i < 16+int(a[0]); i++ {
+        digestDS.Write(salt)
+    }
+    s := repeatTo(digestDS.Sum(nil), len(salt))
+
+    c := a
+    for i := 0; i < rounds; i++ {
+        digestC := sha512.New()
+        if i&1 != 0 {
+            digestC.Write(p)
+        } else {
+            digestC.Write(c)
+        }
+        if i%3 != 0 {
+            digestC.Write(s)
+        }
+        if i%7 != 0 {
+            digestC.Write(p)
+        }
+        if i&1 != 0 {
+            digestC.Write(c)
+        } else {
+            digestC.Write(p)
+        }
+        c = digestC.Sum(nil)
+    }
+
+    var out strings.Builder
+    out.WriteString("$6$")
+    if customRounds {
+        out.WriteString("rounds=" + strconv.Itoa(rounds) + "$")
+    }
+    out.Write(salt)
+    out.WriteByte('$')
+
+    order := [][3]int{
+        {0, 21, 42}, {22, 43, 1}, {44, 2, 23}, {3, 24, 45}, {25, 46, 4},
+        {47, 5, 26}, {6, 27, 48}, {28, 49, 7}, {50, 8, 29}, {9, 30, 51},
+        {31, 52, 10}, {53, 11, 32}, {12, 33, 54}, {34, 55, 13}, {56, 14, 35},
+        {15, 36, 57}, {37, 58, 16}, {59, 17, 38}, {18, 39, 60}, {40, 61, 19},
+        {62, 20, 41},
+    }
+    for _, o := range order {
+        writeCrypt64(&out, uint(c[o[0]])<<16|uint(c[o[1]])<<8|uint(c[o[2]]), 4)
+    }
+    writeCrypt64(&out, uint(c[63]), 2)
+
+    return out.String()
+}
+
+func repeatTo(block []byte, length int) []byte {
+    out := make([]byte, 0, length)
+    for len(out) < length {
+        out = append(out, block[:min(len(block), length-len(out))]...)
+    }
+    return out
+}
+
+func writeCrypt64(out *strings.Builder, v uint, n int) {
+    for ; n > 0; n-- {
+        out.WriteByte(cryptAlphabet[v&0x3f])
+        v >>= 6
+    }
+}
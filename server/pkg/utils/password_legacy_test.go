@@ -0,0 +1,92 @@
+package utils
+
+import "testing"
+
+// firebaseParams and the hash below are the sample project from Firebase's
+// scrypt documentation.
+var firebaseParams = LegacyHashParams{
+    Firebase: FirebaseScryptParams{
+        SignerKey:     "jxspr8Ki0RYycVU8zykbdLGjFQ3McFUH0uiiTvC8pVMXAn210wjLNmdZJzxUECKbm0QsEmYUSDzZvpjeJ9WmXA==",
+        SaltSeparator: "Bw==",
+        Rounds:        8,
+        MemCost:       14,
+    },
+}
+
+const firebaseHash = "42xEC+ixf3L2lw==$lSrfV15cpx95/sZS2W9c9Kp6i/LVgQNDNC/qzrCnh1SAyZvqmZqAjTdn3aoItz+VHjoZilo78198JAdRuid5lQ=="
+
+func TestCheckPasswordHashWithAlgo(t *testing.T) {
+    bcryptHash, err := HashPassword("correct horse")
+    if err != nil {
+        t.Fatalf("HashPassword: %v", err)
+    }
+
+    tests := []struct {
+        name     string
+        algo     string
+        password string
+        hash     string
+        params   LegacyHashParams
+        want     bool
+        wantErr  bool
+    }{
+        {name: "bcrypt", algo: HashBcrypt, password: "correct horse", hash: bcryptHash, want: true},
+        {name: "bcrypt by default", algo: "", password: "correct horse", hash: bcryptHash, want: true},
+        {name: "bcrypt wrong password", algo: HashBcrypt, password: "wrong", hash: bcryptHash},
+
+        {name: "firebase", algo: HashFirebaseScrypt, password: "user1password", hash: firebaseHash, params: firebaseParams, want: true},
+        {name: "firebase wrong password", algo: HashFirebaseScrypt, password: "user2password", hash: firebaseHash, params: firebaseParams},
+        {name: "firebase without signer key", algo: HashFirebaseScrypt, password: "user1password", hash: firebaseHash, wantErr: true},
+        {name: "firebase without salt", algo: HashFirebaseScrypt, password: "user1password", hash: "lSrfV15cpx95", params: firebaseParams, wantErr: true},
+        {name: "firebase bad base64", algo: HashFirebaseScrypt, password: "user1password", hash: "42xEC$not base64!", params: firebaseParams, wantErr: true},
+
+        {name: "django sha256", algo: HashDjangoPBKDF2, password: "correct horse", hash: "pbkdf2_sha256$1000$seasalt$mQnueSakb748zqBAC1tmWVZsZbi2zPGZarEzTGdfmso=", want: true},
+        {name: "django sha1", algo: HashDjangoPBKDF2, password: "correct horse", hash: "pbkdf2_sha1$1000$seasalt$iQvkNOF1wEL4Khh8eogJ8rUhipM=", want: true},
+        {name: "django wrong password", algo: HashDjangoPBKDF2, password: "battery staple", hash: "pbkdf2_sha256$1000$seasalt$mQnueSakb748zqBAC1tmWVZsZbi2zPGZarEzTGdfmso="},
+        {name: "django unknown hasher", algo: HashDjangoPBKDF2, password: "correct horse", hash: "argon2$1000$seasalt$mQnueSakb748", wantErr: true},
+        {name: "django bad iterations", algo: HashDjangoPBKDF2, password: "correct horse", hash: "pbkdf2_sha256$0$seasalt$mQnueSakb748", wantErr: true},
+        {name: "django missing field", algo: HashDjangoPBKDF2, password: "correct horse", hash: "pbkdf2_sha256$1000$mQnueSakb748", wantErr: true},
+
+        // Test vectors from the SHA-crypt specification
+        {name: "sha512-crypt", algo: HashSHA512Crypt, password: "Hello world!", hash: "$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1", want: true},
+        {name: "sha512-crypt rounds", algo: HashSHA512Crypt, password: "Hello world!", hash: "$6$rounds=10000$saltstringsaltst$OW1/O6BYHV6BcXZu8QVeXbDWra3Oeqh0sbHbbMCVNSnCM/UrjmM0Dp8vOuZeHBy/YTBmSK6H9qs/y3RnOaw5v.", want: true},
+        {name: "sha512-crypt long salt", algo: HashSHA512Crypt, password: "This is just a test", hash: "$6$rounds=5000$toolongsaltstrin$lQ8jolhgVRVhY4b5pZKaysCLi0QBxGoNeKQzQ3glMhwllF7oGDZxUhx1yxdYcz/e1JSbq3y6JMxxl8audkUEm0", want: true},
+        {name: "sha512-crypt wrong password", algo: HashSHA512Crypt, password: "Hello world", hash: "$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1"},
+        {name: "sha512-crypt other scheme", algo: HashSHA512Crypt, password: "Hello world!", hash: "$5$saltstring$5B8vYYiY.CVt1RlTTf8KbXBH3hsxY/GNooZF8hPcRWL", wantErr: true},
+        {name: "sha512-crypt bad rounds", algo: HashSHA512Crypt, password: "Hello world!", hash: "$6$rounds=many$saltstring$svn8", wantErr: true},
+
+        {name: "unknown algorithm", algo: "md5", password: "x", hash: "x", wantErr: true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got, err := CheckPasswordHashWithAlgo(tt.algo, tt.password, tt.hash, tt.params)
+            if (err != nil) != tt.wantErr {
+                t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+            }
+            if got != tt.want {
+                t.Errorf("CheckPasswordHashWithAlgo() = %v, want %v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestIsSupportedHashAlgo(t *testing.T) {
+    tests := []struct {
+        algo string
+        want bool
+    }{
+        {HashBcrypt, true},
+        {HashFirebaseScrypt, true},
+        {HashDjangoPBKDF2, true},
+        {HashSHA512Crypt, true},
+        {"", false},
+        {"md5", false},
+    }
+
+    for _, tt := range tests {
+        if got := IsSupportedHashAlgo(tt.algo); got != tt.want {
+            t.Errorf("IsSupportedHashAlgo(%q) = %v, want %v", tt.algo, got, tt.want)
+        }
+    }
+}
@@ -0,0 +1,134 @@
+package validator
+
+import (
+    "regexp"
+    "sort"
+    "strings"
+    "time"
+
+    "github.com/go-playground/validator/v10"
+)
+
+// Attribute value types understood by ValidateAttributes.
+const (
+    AttributeString  = "string"
+    AttributeNumber  = "number"
+    AttributeBoolean = "boolean"
+    AttributeDate    = "date"
+    AttributeEnum    = "enum"
+)
+
+var attributeKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// AttributeRule constrains a single custom attribute value.
+type AttributeRule struct {
+    Key      string
+    Type     string
+    Required bool
+    Enum     []string
+    Pattern  string
+}
+
+// ValidateAttributes checks values against rules. Values are expected as
+// decoded from JSON: strings, float64 numbers and bools. Dates are strings
+// in YYYY-MM-DD form. Keys without a rule are rejected.
+func ValidateAttributes(rules []AttributeRule, values map[string]interface{}) error {
+    var validationErrors ValidationErrors
+
+    known := make(map[string]bool, len(rules))
+    for _, rule := range rules {
+        known[rule.Key] = true
+
+        value, ok := values[rule.Key]
+        if !ok || value == nil || value == "" {
+            if rule.Required {
+                validationErrors = append(validationErrors, attributeError(rule.Key, "required", ""))
+            }
+            continue
+        }
+
+        if fe, failed := validateAttribute(rule, value); failed {
+            validationErrors = append(validationErrors, fe)
+        }
+    }
+
+    var unknown []string
+    for key := range values {
+        if !known[key] {
+            unknown = append(unknown, key)
+        }
+    }
+    sort.Strings(unknown)
+    for _, key := range unknown {
+        validationErrors = append(validationErrors, attributeError(key, "unknown_attribute", ""))
+    }
+
+    if len(validationErrors) > 0 {
+        return validationErrors
+    }
+    return nil
+}
+
+// attributeError reports a failed attribute rule. The tag doubles as the
+// message key.
+func attributeError(key, tag, param string) FieldError {
+    return newFieldError("attributes."+key, key, tag, param, tag)
+}
+
+func validateAttribute(rule AttributeRule, value interface{}) (FieldError, bool) {
+    switch rule.Type {
+    case AttributeNumber:
+        if _, ok := value.(float64); !ok {
+            return attributeError(rule.Key, "number", ""), true
+        }
+        return FieldError{}, false
+    case AttributeBoolean:
+        if _, ok := value.(bool); !ok {
+            return attributeError(rule.Key, "boolean", ""), true
+        }
+        return FieldError{}, false
+    }
+
+    s, ok := value.(string)
+    if !ok {
+        return attributeError(rule.Key, "string", ""), true
+    }
+
+    switch rule.Type {
+    case AttributeDate:
+        if _, err := time.Parse("2006-01-02", s); err != nil {
+            return attributeError(rule.Key, "date", ""), true
+        }
+    case AttributeEnum:
+        found := false
+        for _, allowed := range rule.Enum {
+            if s == allowed {
+                found = true
+                break
+            }
+        }
+        if !found {
+            return attributeError(rule.Key, "oneof", strings.Join(rule.Enum, " ")), true
+        }
+    }
+
+    if rule.Pattern != "" {
+        re, err := regexp.Compile(rule.Pattern)
+        if err != nil {
+            return attributeError(rule.Key, "pattern_invalid", ""), true
+        }
+        if !re.MatchString(s) {
+            return attributeError(rule.Key, "pattern", rule.Pattern), true
+        }
+    }
+    return FieldError{}, false
+}
+
+func validateAttributeKey(fl validator.FieldLevel) bool {
+    return attributeKeyPattern.MatchString(fl.Field().String())
+}
+
+func validateRegexp(fl validator.FieldLevel) bool {
+    _, err := regexp.Compile(fl.Field().String())
+    return err == nil
+}
@@ -0,0 +1,135 @@
+package validator
+
+import (
+    "sort"
+    "strconv"
+    "strings"
+
+    "github.com/go-playground/locales/ar"
+    "github.com/go-playground/locales/en"
+    ut "github.com/go-playground/universal-translator"
+)
+
+// messages holds the validation messages of every supported locale, keyed
+// by rule. {0} is the field name and {1} the rule's parameter; {0} must come
+// first in the text. The locales match those of the web client.
+var messages = map[string]map[string]string{
+    "en": {
+        "required":          "{0} is required",
+        "email":             "{0} must be a valid email address",
+        "min":               "{0} must be at least {1} characters long",
+        "max":               "{0} must be at most {1} characters long",
+        "password":          "{0} must contain at least 8 characters with uppercase, lowercase, number and special character",
+        "gte":               "{0} must be greater than or equal to {1}",
+        "lte":               "{0} must be less than or equal to {1}",
+        "oneof":             "{0} must be one of: {1}",
+        "attrkey":           "{0} must start with a lowercase letter and contain only lowercase letters, digits and underscores",
+        "flagname":          "{0} must be at most 64 lowercase letters, digits and underscores, starting with a letter",
+        "regexp":            "{0} must be a valid regular expression",
+        "username":          "{0} must be 3-32 characters of letters, digits, '.', '_' or '-', starting with a letter",
+        "username_reserved": "{0} is reserved",
+        "number":            "{0} must be a number",
+        "boolean":           "{0} must be true or false",
+        "string":            "{0} must be a string",
+        "date":              "{0} must be a date in YYYY-MM-DD format",
+        "pattern":           "{0} does not match the required format",
+        "pattern_invalid":   "{0} has an invalid pattern",
+        "unknown_attribute": "{0} is not a defined attribute",
+        "invalid":           "{0} is invalid",
+    },
+    "ar": {
+        "required":          "{0} مطلوب",
+        "email":             "يجب أن يكون {0} عنوان بريد إلكتروني صالحًا",
+        "min":               "يجب أن يتكون {0} من {1} أحرف على الأقل",
+        "max":               "يجب ألا يتجاوز {0} {1} حرفًا",
+        "password":          "يجب أن يحتوي {0} على 8 أحرف على الأقل تشمل حرفًا كبيرًا وحرفًا صغيرًا ورقمًا ورمزًا خاصًا",
+        "gte":               "يجب أن يكون {0} أكبر من أو يساوي {1}",
+        "lte":               "يجب أن يكون {0} أصغر من أو يساوي {1}",
+        "oneof":             "يجب أن يكون {0} واحدًا من: {1}",
+        "attrkey":           "يجب أن يبدأ {0} بحرف صغير وأن يحتوي فقط على أحرف صغيرة وأرقام وشرطات سفلية",
+        "flagname":          "يجب ألا يتجاوز {0} 64 حرفًا من الأحرف الصغيرة والأرقام والشرطات السفلية، وأن يبدأ بحرف",
+        "regexp":            "يجب أن يكون {0} تعبيرًا نمطيًا صالحًا",
+        "username":          "يجب أن يتكون {0} من 3 إلى 32 حرفًا من الأحرف أو الأرقام أو '.' أو '_' أو '-'، وأن يبدأ بحرف",
+        "username_reserved": "{0} محجوز",
+        "number":            "يجب أن يكون {0} رقمًا",
+        "boolean":           "يجب أن تكون قيمة {0} true أو false",
+        "string":            "يجب أن يكون {0} نصًا",
+        "date":              "يجب أن يكون {0} تاريخًا بالصيغة YYYY-MM-DD",
+        "pattern":           "{0} لا يطابق الصيغة المطلوبة",
+        "pattern_invalid":   "{0} يحتوي على نمط غير صالح",
+        "unknown_attribute": "{0} ليست سمة معرّفة",
+        "invalid":           "{0} غير صالح",
+    },
+}
+
+var (
+    uni     *ut.UniversalTranslator
+    english ut.Translator
+)
+
+func init() {
+    uni = ut.New(en.New(), en.New(), ar.New())
+    for locale, texts := range messages {
+        trans, _ := uni.GetTranslator(locale)
+        for key, text := range texts {
+            if err := trans.Add(key, text, false); err != nil {
+                panic(err)
+            }
+        }
+    }
+    english = uni.GetFallback()
+}
+
+// translate renders the message of fe in the language of trans, falling
+// back to English for a message the locale lacks.
+func (fe FieldError) translate(trans ut.Translator) string {
+    if msg, err := trans.T(fe.key, fe.name, fe.Param); err == nil {
+        return msg
+    }
+    msg, _ := english.T(fe.key, fe.name, fe.Param)
+    return msg
+}
+
+// Localize returns a copy of e with every message in the given locale.
+func (e ValidationErrors) Localize(locale string) ValidationErrors {
+    trans, _ := uni.FindTranslator(locale)
+    localized := make(ValidationErrors, len(e))
+    for i, fe := range e {
+        fe.Message = fe.translate(trans)
+        localized[i] = fe
+    }
+    return localized
+}
+
+// MatchLocale picks the supported locale that best fits an Accept-Language
+// header, or "en" when none does.
+func MatchLocale(acceptLanguage string) string {
+    type weighted struct {
+        tag string
+        q   float64
+    }
+
+    var tags []weighted
+    for _, part := range strings.Split(acceptLanguage, ",") {
+        tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+        q := 1.0
+        if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+            if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+                q = parsed
+            }
+        }
+        // Only the language matters: "ar-SA" is served as "ar"
+        tag, _, _ = strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+        if tag != "" && q > 0 {
+            tags = append(tags, weighted{tag, q})
+        }
+    }
+    sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+    for _, t := range tags {
+        if _, ok := messages[t.tag]; ok {
+            return t.tag
+        }
+    }
+    return "en"
+}
@@ -0,0 +1,194 @@
+package validator
+
+import (
+    "errors"
+    "strings"
+    "testing"
+)
+
+type testRule struct {
+    Percentage int `json:"percentage" validate:"gte=0,lte=100"`
+}
+
+type testSignup struct {
+    Email    string     `json:"email" validate:"required,email"`
+    Password string     `json:"password" validate:"required,min=8"`
+    Username string     `json:"username" validate:"omitempty,username"`
+    Role     string     `json:"role" validate:"omitempty,oneof=user admin"`
+    Rules    []testRule `json:"rules" validate:"dive"`
+    Nickname string     `json:"nickname" validate:"omitempty,alpha"`
+}
+
+func TestValidateMessages(t *testing.T) {
+    valid := testSignup{Email: "jane@example.com", Password: "longenough"}
+
+    tests := []struct {
+        name   string
+        modify func(s *testSignup)
+        want   []FieldError
+    }{
+        {
+            name:   "valid",
+            modify: func(s *testSignup) {},
+        },
+        {
+            name:   "required",
+            modify: func(s *testSignup) { s.Email = "" },
+            want:   []FieldError{{Field: "email", Tag: "required", Message: "email is required"}},
+        },
+        {
+            name:   "parameter in message",
+            modify: func(s *testSignup) { s.Password = "short" },
+            want:   []FieldError{{Field: "password", Tag: "min", Param: "8", Message: "password must be at least 8 characters long"}},
+        },
+        {
+            name:   "nested field path",
+            modify: func(s *testSignup) { s.Rules = []testRule{{50}, {150}} },
+            want:   []FieldError{{Field: "rules[1].percentage", Tag: "lte", Param: "100", Message: "percentage must be less than or equal to 100"}},
+        },
+        {
+            name:   "reserved username",
+            modify: func(s *testSignup) { s.Username = "Admin" },
+            want:   []FieldError{{Field: "username", Tag: "username", Message: "username is reserved"}},
+        },
+        {
+            name:   "malformed username",
+            modify: func(s *testSignup) { s.Username = "9lives" },
+            want:   []FieldError{{Field: "username", Tag: "username", Message: "username must be 3-32 characters of letters, digits, '.', '_' or '-', starting with a letter"}},
+        },
+        {
+            name:   "rule without a message",
+            modify: func(s *testSignup) { s.Nickname = "j4ne" },
+            want:   []FieldError{{Field: "nickname", Tag: "alpha", Message: "nickname is invalid"}},
+        },
+        {
+            name: "every failure reported",
+            modify: func(s *testSignup) {
+                s.Email = "jane"
+                s.Role = "owner"
+            },
+            want: []FieldError{
+                {Field: "email", Tag: "email", Message: "email must be a valid email address"},
+                {Field: "role", Tag: "oneof", Param: "user admin", Message: "role must be one of: user admin"},
+            },
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            s := valid
+            tt.modify(&s)
+
+            err := Validate(s)
+            if tt.want == nil {
+                if err != nil {
+                    t.Fatalf("Validate() = %v, want nil", err)
+                }
+                return
+            }
+
+            var got ValidationErrors
+            if !errors.As(err, &got) {
+                t.Fatalf("Validate() = %v, want ValidationErrors", err)
+            }
+            if len(got) != len(tt.want) {
+                t.Fatalf("Validate() = %+v, want %+v", got, tt.want)
+            }
+            for i, want := range tt.want {
+                fe := got[i]
+                if fe.Field != want.Field || fe.Tag != want.Tag || fe.Param != want.Param || fe.Message != want.Message {
+                    t.Errorf("error %d = %+v, want %+v", i, fe, want)
+                }
+            }
+        })
+    }
+}
+
+func TestValidateFieldName(t *testing.T) {
+    err := ValidateField("username", "root", "omitempty,username")
+    if got, want := err.Error(), "username is reserved"; got != want {
+        t.Errorf("ValidateField() = %q, want %q", got, want)
+    }
+}
+
+func TestLocalize(t *testing.T) {
+    err := Validate(testSignup{Email: "jane", Password: "short"})
+    var errs ValidationErrors
+    if !errors.As(err, &errs) {
+        t.Fatalf("Validate() = %v, want ValidationErrors", err)
+    }
+
+    tests := []struct {
+        locale string
+        want   string
+    }{
+        {"en", "email must be a valid email address, password must be at least 8 characters long"},
+        {"ar", "يجب أن يكون email عنوان بريد إلكتروني صالحًا, يجب أن يتكون password من 8 أحرف على الأقل"},
+        // Unsupported locales fall back to English
+        {"fr", "email must be a valid email address, password must be at least 8 characters long"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.locale, func(t *testing.T) {
+            localized := errs.Localize(tt.locale)
+            if got := localized.Error(); got != tt.want {
+                t.Errorf("Localize(%q) = %q, want %q", tt.locale, got, tt.want)
+            }
+            for i := range localized {
+                if localized[i].Field != errs[i].Field || localized[i].Tag != errs[i].Tag {
+                    t.Errorf("Localize(%q) changed field %d: %+v", tt.locale, i, localized[i])
+                }
+            }
+        })
+    }
+
+    if got := errs[0].Message; got != "email must be a valid email address" {
+        t.Errorf("Localize changed the original: %q", got)
+    }
+}
+
+func TestMessagesCoverEveryLocale(t *testing.T) {
+    for locale, texts := range messages {
+        for key := range messages["en"] {
+            text, ok := texts[key]
+            if !ok {
+                t.Errorf("%s has no %q message", locale, key)
+                continue
+            }
+            if !strings.Contains(text, "{0}") {
+                t.Errorf("%s %q message does not name the field: %q", locale, key, text)
+            }
+        }
+        for key := range texts {
+            if _, ok := messages["en"][key]; !ok {
+                t.Errorf("%s has a %q message with no English fallback", locale, key)
+            }
+        }
+    }
+}
+
+func TestMatchLocale(t *testing.T) {
+    tests := []struct {
+        header string
+        want   string
+    }{
+        {"", "en"},
+        {"ar", "ar"},
+        {"ar-SA", "ar"},
+        {"AR-sa", "ar"},
+        {"fr-FR, ar;q=0.8, en;q=0.5", "ar"},
+        {"en;q=0.4, ar;q=0.9", "ar"},
+        {"ar;q=0, en", "en"},
+        {"ar, en", "ar"},
+        {"en, ar", "en"},
+        {"*", "en"},
+        {"de, fr", "en"},
+        {"ar;q=oops", "ar"},
+    }
+
+    for _, tt := range tests {
+        if got := MatchLocale(tt.header); got != tt.want {
+            t.Errorf("MatchLocale(%q) = %q, want %q", tt.header, got, tt.want)
+        }
+    }
+}
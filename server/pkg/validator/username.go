@@ -0,0 +1,56 @@
+package validator
+
+import (
+    "regexp"
+    "strings"
+
+    "github.com/go-playground/validator/v10"
+)
+
+// Usernames start with a letter, are 3-32 characters long and may contain
+// single '.', '_' or '-' separators between letters and digits.
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9]*(?:[._-][a-zA-Z0-9]+)*$`)
+
+// reservedUsernames cannot be registered because they could be mistaken for
+// staff accounts or collide with routes.
+var reservedUsernames = map[string]bool{
+    "admin":         true,
+    "administrator": true,
+    "root":          true,
+    "system":        true,
+    "support":       true,
+    "help":          true,
+    "security":      true,
+    "moderator":     true,
+    "staff":         true,
+    "api":           true,
+    "auth":          true,
+    "login":         true,
+    "logout":        true,
+    "register":      true,
+    "signup":        true,
+    "user":          true,
+    "users":         true,
+    "profile":       true,
+    "settings":      true,
+    "me":            true,
+    "null":          true,
+    "undefined":     true,
+    "anonymous":     true,
+    "noreply":       true,
+    "postmaster":    true,
+    "webmaster":     true,
+}
+
+// IsReservedUsername reports whether username is reserved, ignoring case.
+func IsReservedUsername(username string) bool {
+    return reservedUsernames[strings.ToLower(username)]
+}
+
+func validateUsername(fl validator.FieldLevel) bool {
+    username := fl.Field().String()
+    if len(username) < 3 || len(username) > 32 {
+        return false
+    }
+    return usernamePattern.MatchString(username) && !IsReservedUsername(username)
+}
@@ -2,7 +2,6 @@ package validator
 
 import (
     "errors"
-    "fmt"
     "reflect"
     "strings"
 
@@ -25,44 +24,101 @@ func init() {
 
     // Register custom validators
     validate.RegisterValidation("password", validatePassword)
+    validate.RegisterValidation("attrkey", validateAttributeKey)
+    validate.RegisterValidation("regexp", validateRegexp)
+    validate.RegisterValidation("username", validateUsername)
+    validate.RegisterAlias("flagname", "max=64,attrkey")
 }
 
+// FieldError describes why one field failed validation. Field is the JSON
+// path of the field, Tag the rule it broke and Param the rule's argument,
+// if any. Message is in English until Localize is called.
+type FieldError struct {
+    Field   string `json:"field"`
+    Tag     string `json:"tag"`
+    Param   string `json:"param,omitempty"`
+    Message string `json:"message"`
+
+    // name is the field as it appears in messages and key the message
+    // to use, which differs from Tag for some rules.
+    name string
+    key  string
+}
+
+func newFieldError(field, name, tag, param, key string) FieldError {
+    fe := FieldError{Field: field, Tag: tag, Param: param, name: name, key: key}
+    fe.Message = fe.translate(english)
+    return fe
+}
+
+// ValidationErrors lists every field that failed validation. Its Error
+// joins the messages, for callers that only want text.
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+    messages := make([]string, len(e))
+    for i, fe := range e {
+        messages[i] = fe.Message
+    }
+    return strings.Join(messages, ", ")
+}
+
+// Validate checks s against its validate tags. Failures are returned as
+// ValidationErrors.
 func Validate(s interface{}) error {
-    if err := validate.Struct(s); err != nil {
-        var validationErrors []string
-        
-        for _, err := range err.(validator.ValidationErrors) {
-            validationErrors = append(validationErrors, formatValidationError(err))
+    return convert(validate.Struct(s), "")
+}
+
+// ValidateField checks a single value against tag, reporting failures as
+// ValidationErrors for the named field.
+func ValidateField(name string, value interface{}, tag string) error {
+    return convert(validate.Var(value, tag), name)
+}
+
+// convert turns go-playground errors into ValidationErrors. name replaces
+// the empty field name of errors from validate.Var.
+func convert(err error, name string) error {
+    var fieldErrors validator.ValidationErrors
+    if !errors.As(err, &fieldErrors) {
+        return err
+    }
+
+    validationErrors := make(ValidationErrors, 0, len(fieldErrors))
+    for _, err := range fieldErrors {
+        field, leaf := fieldPath(err), err.Field()
+        if name != "" {
+            field, leaf = name, name
         }
-        
-        return errors.New(strings.Join(validationErrors, ", "))
+        validationErrors = append(validationErrors, newFieldError(field, leaf, err.Tag(), err.Param(), messageKey(err)))
     }
-    return nil
+    return validationErrors
 }
 
-func formatValidationError(err validator.FieldError) string {
-    field := err.Field()
-    
+// fieldPath returns the JSON path of the failing field without the name of
+// the top-level struct, such as "rules[0].percentage".
+func fieldPath(err validator.FieldError) string {
+    ns := err.Namespace()
+    if i := strings.Index(ns, "."); i >= 0 {
+        return ns[i+1:]
+    }
+    return ns
+}
+
+// messageKey picks the message for a failed rule. Tags without a message of
+// their own fall back to a generic one.
+func messageKey(err validator.FieldError) string {
     switch err.Tag() {
-    case "required":
-        return fmt.Sprintf("%s is required", field)
-    case "email":
-        return fmt.Sprintf("%s must be a valid email address", field)
-    case "min":
-        return fmt.Sprintf("%s must be at least %s characters long", field, err.Param())
-    case "max":
-        return fmt.Sprintf("%s must be at most %s characters long", field, err.Param())
-    case "password":
-        return fmt.Sprintf("%s must contain at least 8 characters with uppercase, lowercase, number and special character", field)
-    case "gte":
-        return fmt.Sprintf("%s must be greater than or equal to %s", field, err.Param())
-    case "lte":
-        return fmt.Sprintf("%s must be less than or equal to %s", field, err.Param())
-    case "oneof":
-        return fmt.Sprintf("%s must be one of: %s", field, err.Param())
-    default:
-        return fmt.Sprintf("%s is invalid", field)
+    case "required_if", "required_with", "required_without":
+        return "required"
+    case "username":
+        if value, ok := err.Value().(string); ok && IsReservedUsername(value) {
+            return "username_reserved"
+        }
+    }
+    if _, ok := messages["en"][err.Tag()]; ok {
+        return err.Tag()
     }
+    return "invalid"
 }
 
 // Custom password validator
@@ -16,8 +16,19 @@ if [ ! -f .env.production ]; then
     exit 1
 fi
 
-# Load production environment
-export $(cat .env.production | grep -v '#' | xargs)
+# Secrets are mounted from files by docker-compose.prod.yml rather than
+# exported into the environment
+for secret in database_url jwt_secret db_password; do
+    if [ ! -s "secrets/$secret.txt" ]; then
+        echo "Error: secrets/$secret.txt not found"
+        exit 1
+    fi
+done
+
+# Only the non-secret settings the script itself needs are read here;
+# docker-compose reads the rest with --env-file for interpolation
+DB_NAME=$(grep '^DB_NAME=' .env.production | cut -d= -f2-)
+COMPOSE="docker-compose --env-file .env.production -f $COMPOSE_FILE"
 
 # Build Docker image
 echo "Building Docker image..."
@@ -25,15 +36,15 @@ docker build -f docker/Dockerfile -t "$DOCKER_IMAGE" .
 
 # Backup database
 echo "Creating database backup..."
-docker-compose -f "$COMPOSE_FILE" exec -T db pg_dump -U postgres "$DB_NAME" | gzip > "backup_pre_deploy_$(date +%Y%m%d_%H%M%S).sql.gz"
+$COMPOSE exec -T db pg_dump -U postgres "$DB_NAME" | gzip > "backup_pre_deploy_$(date +%Y%m%d_%H%M%S).sql.gz"
 
 # Pull latest images
 echo "Pulling latest images..."
-docker-compose -f "$COMPOSE_FILE" pull
+$COMPOSE pull
 
 # Deploy with zero downtime
 echo "Deploying application..."
-docker-compose -f "$COMPOSE_FILE" up -d --no-deps app
+$COMPOSE up -d --no-deps app
 
 # Wait for health check
 echo "Waiting for application to be healthy..."
@@ -45,7 +56,7 @@ if curl -f http://localhost:8080/health > /dev/null 2>&1; then
 else
     echo "Application health check failed"
     echo "Rolling back..."
-    docker-compose -f "$COMPOSE_FILE" rollback
+    $COMPOSE rollback
     exit 1
 fi
 